@@ -0,0 +1,187 @@
+// Copyright 2019 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package klo
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Collation specifies how to order strings when sorting.
+type Collation int
+
+// Supported collations. The zero value CollationNatural orders strings
+// naturally, so that "a2" sorts before "a10".
+const (
+	CollationNatural Collation = iota // natural order, comparing runs of digits by their numeric values.
+	CollationLexical                  // lexical order, comparing strings bytewise.
+	CollationNoCase                   // case-insensitive natural order.
+	CollationUnicode                  // language-independent Unicode collation order.
+)
+
+// ParseCollation returns the collation for the given name, which must be
+// either "natural", "lexical", "nocase", or "unicode".
+func ParseCollation(s string) (Collation, error) {
+	switch s {
+	case "natural":
+		return CollationNatural, nil
+	case "lexical":
+		return CollationLexical, nil
+	case "nocase":
+		return CollationNoCase, nil
+	case "unicode":
+		return CollationUnicode, nil
+	}
+	return CollationNatural, fmt.Errorf("unexpected collation %q, expected 'natural', 'lexical', 'nocase', or 'unicode'", s)
+}
+
+// key returns the collation key of the string s, which then needs to be
+// compared to other collation keys of the same collation using compare.
+// Computing the keys only once up front keeps comparing strings cheap.
+func (c Collation) key(s string) string {
+	switch c {
+	case CollationNoCase:
+		return strings.Map(foldRune, s)
+	case CollationUnicode:
+		return collationKey(s)
+	}
+	return s
+}
+
+// compare compares two collation keys, as returned by key.
+func (c Collation) compare(a, b string) int {
+	switch c {
+	case CollationNatural, CollationNoCase:
+		return naturalCompare(a, b)
+	}
+	return strings.Compare(a, b)
+}
+
+// foldRune returns the case-folded rune r, so that all runes differing only
+// in case fold to the same (lower case) rune, such as "K", "k", and the
+// Kelvin sign.
+func foldRune(r rune) rune {
+	return unicode.ToLower(unicode.ToUpper(r))
+}
+
+// collationKey returns the Unicode collation key of the string s, so that
+// comparing keys bytewise orders strings similar to the default ordering of
+// the Unicode Collation Algorithm (UCA), see https://unicode.org/reports/tr10.
+// Only Latin letters get decomposed into their base letters and accents,
+// while other scripts simply order by their code points. Comparing keys
+// works in three levels: first, strings get compared by their (case-folded)
+// base letters, where whitespace, punctuation, and symbols sort before
+// digits, and digits before letters; next, unaccented letters sort before
+// accented letters; and finally lower case sorts before upper case. Control
+// characters are ignored.
+func collationKey(s string) string {
+	var primary, secondary, tertiary strings.Builder
+	for _, r := range s {
+		if unicode.IsControl(r) {
+			continue
+		}
+		base, accented := latinBase(r)
+		if unicode.Is(unicode.Mn, r) {
+			// Combining marks, as found in decomposed text, only count as
+			// accents.
+			secondary.WriteRune(r)
+			continue
+		}
+		upper := unicode.IsUpper(r)
+		for _, b := range base {
+			primary.WriteByte(primaryClass(b))
+			primary.WriteRune(foldRune(b))
+			if accented {
+				secondary.WriteRune(foldRune(r))
+				accented = false
+			} else {
+				secondary.WriteByte(1)
+			}
+			if upper {
+				tertiary.WriteByte(2)
+			} else {
+				tertiary.WriteByte(1)
+			}
+		}
+	}
+	// The levels are separated by a zero byte, which sorts before all
+	// weights, so that shorter strings sort before longer strings on the
+	// same level.
+	return primary.String() + "\x00" + secondary.String() + "\x00" + tertiary.String()
+}
+
+// primaryClass returns the primary weight class of the rune r: whitespace,
+// punctuation, and symbols sort first, then digits, and finally letters and
+// all other runes.
+func primaryClass(r rune) byte {
+	switch {
+	case unicode.IsSpace(r) || unicode.IsPunct(r) || unicode.IsSymbol(r):
+		return 1
+	case unicode.IsDigit(r):
+		return 2
+	}
+	return 3
+}
+
+// latinBase returns the base letter(s) of the rune r, as well as whether r is
+// an accented letter or ligature. Runes other than accented Latin letters and
+// ligatures are returned as they are.
+func latinBase(r rune) (string, bool) {
+	if base, ok := latinBases[r]; ok {
+		return base, true
+	}
+	return string(r), false
+}
+
+// latinBases maps accented Latin letters and ligatures from the Latin-1
+// Supplement and Latin Extended-A blocks to their base letters.
+var latinBases = func() map[rune]string {
+	bases := map[rune]string{}
+	for _, d := range []struct {
+		runes string
+		base  string
+	}{
+		{"ÀÁÂÃÄÅĀĂĄ", "A"}, {"àáâãäåāăą", "a"},
+		{"ÇĆĈĊČ", "C"}, {"çćĉċč", "c"},
+		{"ĎĐÐ", "D"}, {"ďđð", "d"},
+		{"ÈÉÊËĒĔĖĘĚ", "E"}, {"èéêëēĕėęě", "e"},
+		{"ĜĞĠĢ", "G"}, {"ĝğġģ", "g"},
+		{"ĤĦ", "H"}, {"ĥħ", "h"},
+		{"ÌÍÎÏĨĪĬĮİ", "I"}, {"ìíîïĩīĭįı", "i"},
+		{"Ĵ", "J"}, {"ĵ", "j"},
+		{"Ķ", "K"}, {"ķĸ", "k"},
+		{"ĹĻĽĿŁ", "L"}, {"ĺļľŀł", "l"},
+		{"ÑŃŅŇŊ", "N"}, {"ñńņňŋ", "n"},
+		{"ÒÓÔÕÖØŌŎŐ", "O"}, {"òóôõöøōŏő", "o"},
+		{"ŔŖŘ", "R"}, {"ŕŗř", "r"},
+		{"ŚŜŞŠ", "S"}, {"śŝşšſ", "s"},
+		{"ŢŤŦ", "T"}, {"ţťŧ", "t"},
+		{"ÙÚÛÜŨŪŬŮŰŲ", "U"}, {"ùúûüũūŭůűų", "u"},
+		{"Ŵ", "W"}, {"ŵ", "w"},
+		{"ÝŶŸ", "Y"}, {"ýÿŷ", "y"},
+		{"ŹŻŽ", "Z"}, {"źżž", "z"},
+		{"Æ", "AE"}, {"æ", "ae"},
+		{"Œ", "OE"}, {"œ", "oe"},
+		{"Ĳ", "IJ"}, {"ĳ", "ij"},
+		{"Þ", "TH"}, {"þ", "th"},
+		{"ß", "ss"},
+	} {
+		for _, r := range d.runes {
+			bases[r] = d.base
+		}
+	}
+	return bases
+}()
@@ -0,0 +1,64 @@
+// Copyright 2019 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package klo
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("collations", func() {
+
+	expectCollated := func(c Collation, strs ...string) {
+		GinkgoHelper()
+		for idx := 0; idx < len(strs)-1; idx++ {
+			a, b := c.key(strs[idx]), c.key(strs[idx+1])
+			Expect(c.compare(a, b)).To(BeNumerically("<", 0), "%q < %q", strs[idx], strs[idx+1])
+			Expect(c.compare(b, a)).To(BeNumerically(">", 0), "%q > %q", strs[idx+1], strs[idx])
+		}
+	}
+
+	It("parses collations", func() {
+		Expect(ParseCollation("natural")).To(Equal(CollationNatural))
+		Expect(ParseCollation("lexical")).To(Equal(CollationLexical))
+		Expect(ParseCollation("nocase")).To(Equal(CollationNoCase))
+		Expect(ParseCollation("unicode")).To(Equal(CollationUnicode))
+		_, err := ParseCollation("random")
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("orders naturally", func() {
+		expectCollated(CollationNatural, "Zebra", "a2", "a10", "apple")
+	})
+
+	It("orders lexically", func() {
+		expectCollated(CollationLexical, "0a1f", "0a9", "Zebra", "a10", "a2")
+	})
+
+	It("orders case-insensitively", func() {
+		expectCollated(CollationNoCase, "a2", "A10", "apple", "Zebra")
+		Expect(CollationNoCase.key("Straße")).To(Equal(CollationNoCase.key("STRAßE")))
+		Expect(CollationNoCase.key("K")).To(Equal("k"))
+	})
+
+	It("orders by Unicode collation keys", func() {
+		expectCollated(CollationUnicode,
+			"-bar", "_foo", "10", "9", "a", "A", "á", "Á", "Äpfel", "apple",
+			"resume", "Resume", "résumé", "strasse", "Strasse", "straße", "zoo", "Zürich")
+		Expect(CollationUnicode.key("a\x07b")).To(Equal(CollationUnicode.key("ab")))
+		expectCollated(CollationUnicode, "resume", "résumé")
+	})
+
+})
@@ -0,0 +1,265 @@
+// Copyright 2019 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package klo
+
+import (
+	"iter"
+	"reflect"
+)
+
+// collection normalizes the value v to be printed by following pointers and
+// interfaces, and then returns the items of v if v turns out to be a
+// collection. Collections are slices, arrays, maps, and Kubernetes-style List
+// objects, that is, structs with an exported Items slice or array field. Map
+// items are returned as a slice of MapEntry items, sorted by key. If v isn't
+// a collection, then collection returns the normalized v itself, with ok
+// being false; for nil values, the returned value is the zero reflect.Value.
+func collection(v interface{}) (items reflect.Value, ok bool) {
+	val := indirect(valueOf(v))
+	switch val.Kind() {
+	case reflect.Slice, reflect.Array:
+		return val, true
+	case reflect.Map:
+		return reflect.ValueOf(sortedMapEntries(val)), true
+	case reflect.Struct:
+		if items, ok := listItems(val); ok {
+			return items, true
+		}
+	}
+	return val, false
+}
+
+// valueOf returns the reflect.Value of v, unless v already is a
+// reflect.Value.
+func valueOf(v interface{}) reflect.Value {
+	if val, ok := v.(reflect.Value); ok {
+		return val
+	}
+	return reflect.ValueOf(v)
+}
+
+// indirect follows pointers and interfaces until it reaches a value that is
+// neither a pointer nor an interface, or a nil pointer or interface; in the
+// latter case, it returns the zero reflect.Value.
+func indirect(val reflect.Value) reflect.Value {
+	for val.Kind() == reflect.Ptr || val.Kind() == reflect.Interface {
+		if val.IsNil() {
+			return reflect.Value{}
+		}
+		val = val.Elem()
+	}
+	return val
+}
+
+// listItems returns the Items of a Kubernetes-style List object, and true;
+// otherwise, false if the struct value isn't a List object. Items might also
+// be interface values holding slices, as in List objects wrapping items.
+func listItems(val reflect.Value) (reflect.Value, bool) {
+	field, ok := val.Type().FieldByName("Items")
+	if !ok || !field.IsExported() {
+		return reflect.Value{}, false
+	}
+	items := val.FieldByIndex(field.Index)
+	if items.Kind() == reflect.Interface {
+		items = items.Elem()
+	}
+	if kind := items.Kind(); kind != reflect.Slice && kind != reflect.Array {
+		return reflect.Value{}, false
+	}
+	return items, true
+}
+
+// withItems returns a copy of the Kubernetes-style List object v with its
+// Items replaced by the specified items, and true. If v isn't a List object,
+// or if its Items field is an array of a different length, then withItems
+// returns false instead.
+func withItems(v interface{}, items reflect.Value) (reflect.Value, bool) {
+	val := indirect(valueOf(v))
+	if val.Kind() != reflect.Struct {
+		return reflect.Value{}, false
+	}
+	if _, ok := listItems(val); !ok {
+		return reflect.Value{}, false
+	}
+	field, _ := val.Type().FieldByName("Items")
+	list := reflect.New(val.Type()).Elem()
+	list.Set(val)
+	dst := list.FieldByIndex(field.Index)
+	switch {
+	case dst.Kind() == reflect.Interface:
+		dst.Set(items)
+	case dst.Kind() == reflect.Slice && items.Kind() == reflect.Slice:
+		dst.Set(items.Convert(dst.Type()))
+	case dst.Kind() == reflect.Array && dst.Len() == items.Len():
+		reflect.Copy(dst, items)
+	default:
+		return reflect.Value{}, false
+	}
+	return list, true
+}
+
+// reshaped returns the specified items, as taken from the collection v, in
+// the shape of that collection: Kubernetes-style List objects keep their
+// wrappers with just their Items replaced, and arrays stay arrays as long as
+// the number of items doesn't change. Otherwise, the items get returned as
+// they are, such as the slice of entries taken from a map.
+func reshaped(v interface{}, items reflect.Value) interface{} {
+	if list, ok := withItems(v, items); ok {
+		return list.Interface()
+	}
+	if val := indirect(valueOf(v)); val.Kind() == reflect.Array && val.Len() == items.Len() {
+		arr := reflect.New(val.Type()).Elem()
+		reflect.Copy(arr, items)
+		return arr.Interface()
+	}
+	return items.Interface()
+}
+
+// item returns the item with the specified index of a collection, unwrapping
+// any items that are reflect.Values themselves.
+func item(items reflect.Value, idx int) interface{} {
+	it := items.Index(idx).Interface()
+	if rv, ok := it.(reflect.Value); ok {
+		return rv.Interface()
+	}
+	return it
+}
+
+// stream returns an iterator over the items of v if v is a stream of items,
+// that is, a channel to receive items from, an iter.Seq, or an iter.Seq2. It
+// additionally returns the type of the items. The items of an iter.Seq2 are
+// MapEntry items, so their keys are available through the "{$key}" pseudo
+// expression. Nil channels and nil iterators are empty streams.
+func stream(v interface{}) (seq iter.Seq[reflect.Value], elemType reflect.Type, ok bool) {
+	val := reflect.ValueOf(v)
+	switch val.Kind() {
+	case reflect.Chan:
+		if val.Type().ChanDir()&reflect.RecvDir == 0 {
+			return nil, nil, false
+		}
+		return func(yield func(reflect.Value) bool) {
+			if val.IsNil() {
+				return
+			}
+			for {
+				it, ok := val.Recv()
+				if !ok || !yield(it) {
+					return
+				}
+			}
+		}, val.Type().Elem(), true
+	case reflect.Func:
+		t := val.Type()
+		if t.NumIn() != 1 || t.NumOut() != 0 {
+			return nil, nil, false
+		}
+		yt := t.In(0)
+		if yt.Kind() != reflect.Func || yt.NumOut() != 1 || yt.Out(0).Kind() != reflect.Bool {
+			return nil, nil, false
+		}
+		switch yt.NumIn() {
+		case 1:
+			elemType = yt.In(0)
+		case 2:
+			elemType = reflect.TypeOf(MapEntry{})
+		default:
+			return nil, nil, false
+		}
+		return func(yield func(reflect.Value) bool) {
+			if val.IsNil() {
+				return
+			}
+			val.Call([]reflect.Value{reflect.MakeFunc(yt, func(args []reflect.Value) []reflect.Value {
+				it := args[0]
+				if len(args) == 2 {
+					it = reflect.ValueOf(MapEntry{
+						Key:   args[0].Interface(),
+						Value: args[1].Interface(),
+					})
+				}
+				return []reflect.Value{reflect.ValueOf(yield(it)).Convert(yt.Out(0))}
+			})})
+		}, elemType, true
+	}
+	return nil, nil, false
+}
+
+// collect returns a slice with all items of v if v is a stream of items,
+// otherwise it returns v unchanged.
+func collect(v interface{}) interface{} {
+	seq, elemType, ok := stream(v)
+	if !ok {
+		return v
+	}
+	sl := reflect.MakeSlice(reflect.SliceOf(elemType), 0, 0)
+	for it := range seq {
+		sl = reflect.Append(sl, it)
+	}
+	return sl.Interface()
+}
+
+// rowObjects calls fn for each item of v if v is a stream of items or a
+// collection, otherwise it calls fn once for v itself, unless v is nil. Byte
+// slices are single values, not collections of bytes. It stops at the first
+// error returned by fn.
+func rowObjects(v interface{}, fn func(obj interface{}) error) error {
+	if seq, _, ok := stream(v); ok {
+		for it := range seq {
+			if err := fn(it.Interface()); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	items, ok := collection(v)
+	if ok && isByteSlice(items) {
+		return fn(items.Interface())
+	}
+	if !ok {
+		if !items.IsValid() {
+			return nil
+		}
+		return fn(items.Interface())
+	}
+	for idx := 0; idx < items.Len(); idx++ {
+		if err := fn(item(items, idx)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isByteSlice returns true if val is a byte slice, such as []byte and
+// json.RawMessage. Encoders encode byte slices as single values instead of
+// as arrays, so byte slices aren't collections of row objects.
+func isByteSlice(val reflect.Value) bool {
+	return val.Kind() == reflect.Slice &&
+		val.Type().Elem().Kind() == reflect.Uint8
+}
+
+// seqOf returns the items of seq as an iter.Seq of the specified item type,
+// so that printers down the chain recognize it as a stream of such items.
+func seqOf(elemType reflect.Type, seq iter.Seq[reflect.Value]) interface{} {
+	yieldType := reflect.FuncOf([]reflect.Type{elemType}, []reflect.Type{reflect.TypeOf(true)}, false)
+	seqType := reflect.FuncOf([]reflect.Type{yieldType}, nil, false)
+	return reflect.MakeFunc(seqType, func(args []reflect.Value) []reflect.Value {
+		for it := range seq {
+			if !args[0].Call([]reflect.Value{it})[0].Bool() {
+				break
+			}
+		}
+		return nil
+	}).Interface()
+}
@@ -0,0 +1,171 @@
+// Copyright 2019 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package klo
+
+import (
+	"iter"
+	"maps"
+	"reflect"
+	"slices"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+type tlistitem struct {
+	A string
+}
+
+type tlist struct {
+	Kind  string
+	Items []tlistitem
+}
+
+var _ = Describe("collections", func() {
+
+	It("normalizes collections", func() {
+		sl := []tlistitem{{A: "foo"}, {A: "bar"}}
+		var iface interface{} = &sl
+		for _, v := range []interface{}{
+			sl, &sl, &iface, reflect.ValueOf(sl),
+			[2]tlistitem{{A: "foo"}, {A: "bar"}},
+			tlist{Items: sl}, &tlist{Items: sl},
+		} {
+			items, ok := collection(v)
+			Expect(ok).To(BeTrue(), "%T", v)
+			Expect(items.Len()).To(Equal(2), "%T", v)
+			Expect(item(items, 1)).To(Equal(tlistitem{A: "bar"}), "%T", v)
+		}
+
+		items, ok := collection(map[string]int{"foo": 1, "bar": 2})
+		Expect(ok).To(BeTrue())
+		Expect(items.Interface()).To(Equal([]MapEntry{{Key: "bar", Value: 2}, {Key: "foo", Value: 1}}))
+
+		items, ok = collection([]reflect.Value{reflect.ValueOf(42)})
+		Expect(ok).To(BeTrue())
+		Expect(item(items, 0)).To(Equal(42))
+	})
+
+	It("returns non-collections", func() {
+		var nilsl *[]tlistitem
+		for _, v := range []interface{}{nil, nilsl} {
+			items, ok := collection(v)
+			Expect(ok).To(BeFalse(), "%T", v)
+			Expect(items.IsValid()).To(BeFalse(), "%T", v)
+		}
+		for _, v := range []interface{}{
+			42, tlistitem{A: "foo"}, &tlistitem{A: "foo"},
+			struct{ Items int }{}, struct{ items []int }{},
+		} {
+			items, ok := collection(v)
+			Expect(ok).To(BeFalse(), "%T", v)
+			Expect(items.IsValid()).To(BeTrue(), "%T", v)
+			Expect(items.Kind()).NotTo(Equal(reflect.Ptr), "%T", v)
+		}
+	})
+
+	It("reshapes items into their original collections", func() {
+		items := reflect.ValueOf([]tlistitem{{A: "bar"}, {A: "foo"}})
+		Expect(reshaped(&tlist{Kind: "List"}, items)).To(Equal(
+			tlist{Kind: "List", Items: []tlistitem{{A: "bar"}, {A: "foo"}}}))
+		Expect(reshaped([2]tlistitem{}, items)).To(Equal([2]tlistitem{{A: "bar"}, {A: "foo"}}))
+		Expect(reshaped([3]tlistitem{}, items)).To(Equal([]tlistitem{{A: "bar"}, {A: "foo"}}))
+		Expect(reshaped(map[string]int{}, reflect.ValueOf([]MapEntry{}))).To(Equal([]MapEntry{}))
+	})
+
+	It("prints and sorts all kinds of collections", func() {
+		ccp := GoodPrinter(NewCustomColumnsPrinterFromSpec("A:{.A}"))
+		sp := GoodPrinter(NewSortingPrinter("{.A}", ccp))
+		values := func() []interface{} {
+			sl := []tlistitem{{A: "foo"}, {A: "bar"}}
+			var iface interface{} = &[]tlistitem{{A: "foo"}, {A: "bar"}}
+			return []interface{}{
+				&sl, &iface,
+				[2]tlistitem{{A: "foo"}, {A: "bar"}},
+				&[2]tlistitem{{A: "foo"}, {A: "bar"}},
+				tlist{Items: []tlistitem{{A: "foo"}, {A: "bar"}}},
+				&tlist{Items: []tlistitem{{A: "foo"}, {A: "bar"}}},
+				[]interface{}{tlistitem{A: "foo"}, &tlistitem{A: "bar"}},
+			}
+		}
+		for _, v := range values() {
+			PrinterPass(ccp, v, `A
+foo
+bar
+`)
+		}
+		for _, v := range values() {
+			PrinterPass(sp, v, `A
+bar
+foo
+`)
+		}
+		var nilsl *[]tlistitem
+		PrinterPass(ccp, nilsl, `A
+`)
+		PrinterPass(sp, nilsl, `A
+`)
+	})
+
+	It("detects streams", func() {
+		ch := make(chan int, 2)
+		ch <- 1
+		ch <- 2
+		close(ch)
+		var nilch chan int
+		var nilseq iter.Seq[int]
+		for _, tt := range []struct {
+			v        interface{}
+			expected interface{}
+		}{
+			{ch, []int{1, 2}},
+			{(<-chan int)(nilch), []int{}},
+			{slices.Values([]string{"foo", "bar"}), []string{"foo", "bar"}},
+			{nilseq, []int{}},
+			{maps.All(map[string]int{"foo": 42}), []MapEntry{{Key: "foo", Value: 42}}},
+			{slices.Values([]interface{}{nil, 42}), []interface{}{nil, 42}},
+		} {
+			_, _, ok := stream(tt.v)
+			Expect(ok).To(BeTrue(), "%T", tt.v)
+			Expect(collect(tt.v)).To(Equal(tt.expected), "%T", tt.v)
+		}
+
+		type yielder func(int) bool
+		type boolish bool
+		Expect(collect(func(yield yielder) { yield(1) })).To(Equal([]int{1}))
+		Expect(collect(func(yield func(int) boolish) { _ = yield(1) && yield(2) })).To(Equal([]int{1, 2}))
+		Expect(collect(func(yield func(int) bool) {
+			for i := range 10 {
+				if !yield(i) {
+					return
+				}
+			}
+		})).To(HaveLen(10))
+	})
+
+	It("doesn't mistake non-streams for streams", func() {
+		for _, v := range []interface{}{
+			nil, 42, []int{}, make(chan<- int), func() {}, func(int) {},
+			func(func(int)) {}, func(func(int) int) {},
+			func(func(int, int, int) bool) {}, func(func(int) bool) bool { return false },
+		} {
+			_, _, ok := stream(v)
+			Expect(ok).To(BeFalse(), "%T", v)
+		}
+		Expect(collect(nil)).To(BeNil())
+		Expect(collect(42)).To(Equal(42))
+	})
+
+})
@@ -0,0 +1,127 @@
+// Copyright 2019 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package klo
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+)
+
+// Color is an ANSI SGR ("Select Graphic Rendition") parameter string, such as
+// "31" for red text, or "1;32" for bold green text.
+type Color string
+
+// A few commonly used ANSI SGR parameters; multiple parameters can be combined
+// by separating them with ";", such as in Bold + ";" + Red.
+const (
+	Bold      Color = "1"
+	Faint     Color = "2"
+	Italic    Color = "3"
+	Underline Color = "4"
+	Red       Color = "31"
+	Green     Color = "32"
+	Yellow    Color = "33"
+	Blue      Color = "34"
+	Magenta   Color = "35"
+	Cyan      Color = "36"
+	White     Color = "37"
+	Gray      Color = "90"
+)
+
+// Sprint returns the text s wrapped in the ANSI escape sequences for this
+// color, resetting all graphic renditions after the text. The empty color
+// returns s unmodified.
+func (c Color) Sprint(s string) string {
+	if c == "" {
+		return s
+	}
+	return "\x1b[" + string(c) + "m" + s + "\x1b[0m"
+}
+
+// ColorMode controls whether printers output ANSI color sequences.
+type ColorMode int
+
+// Supported color modes. The zero value ColorAuto colors output only if the
+// output writer is a terminal and the NO_COLOR environment variable isn't
+// set, see also https://no-color.org.
+const (
+	ColorAuto   ColorMode = iota // color only on terminals, unless NO_COLOR is set.
+	ColorAlways                  // always color, even when NO_COLOR is set.
+	ColorNever                   // never color.
+)
+
+// ParseColorMode returns the color mode for the given "--color" CLI arg value,
+// which must be either "auto", "always", or "never". The empty value is taken
+// as "auto".
+func ParseColorMode(s string) (ColorMode, error) {
+	switch s {
+	case "", "auto":
+		return ColorAuto, nil
+	case "always":
+		return ColorAlways, nil
+	case "never":
+		return ColorNever, nil
+	}
+	return ColorAuto, fmt.Errorf("unexpected color mode %q, expected 'auto', 'always', or 'never'", s)
+}
+
+// enabled returns true if ANSI color sequences should be written to the
+// specified writer in this color mode.
+func (m ColorMode) enabled(w io.Writer) bool {
+	switch m {
+	case ColorAlways:
+		return true
+	case ColorNever:
+		return false
+	}
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// ColorRule colors a column's cells whenever their text matches the rule's
+// Value or, alternatively, the rule's Pattern.
+type ColorRule struct {
+	Value   string         // exact cell text to match.
+	Pattern *regexp.Regexp // optional regular expression to match instead of Value.
+	Color   Color          // color to render matching cells in.
+}
+
+// matches returns true if the cell text matches this color rule.
+func (r ColorRule) matches(s string) bool {
+	if r.Pattern != nil {
+		return r.Pattern.MatchString(s)
+	}
+	return s == r.Value
+}
+
+// cellColor returns the color of the first color rule matching the cell text
+// s, or the empty color if no rule matches.
+func cellColor(s string, rules []ColorRule) Color {
+	for _, rule := range rules {
+		if rule.matches(s) {
+			return rule.Color
+		}
+	}
+	return ""
+}
@@ -0,0 +1,73 @@
+// Copyright 2019 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package klo
+
+import (
+	"bytes"
+	"os"
+	"regexp"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("colors", func() {
+
+	It("wraps text in ANSI color sequences", func() {
+		Expect(Color("").Sprint("foo")).To(Equal("foo"))
+		Expect(Red.Sprint("foo")).To(Equal("\x1b[31mfoo\x1b[0m"))
+		Expect((Bold + ";" + Green).Sprint("foo")).To(Equal("\x1b[1;32mfoo\x1b[0m"))
+	})
+
+	It("parses color modes", func() {
+		Expect(ParseColorMode("")).To(Equal(ColorAuto))
+		Expect(ParseColorMode("auto")).To(Equal(ColorAuto))
+		Expect(ParseColorMode("always")).To(Equal(ColorAlways))
+		Expect(ParseColorMode("never")).To(Equal(ColorNever))
+		_, err := ParseColorMode("sometimes")
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("decides when to color", func() {
+		var buff bytes.Buffer
+		Expect(ColorAuto.enabled(&buff)).To(BeFalse())
+		Expect(ColorNever.enabled(&buff)).To(BeFalse())
+		Expect(ColorAlways.enabled(&buff)).To(BeTrue())
+
+		f, err := os.CreateTemp("", "klo-color-*")
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(func() {
+			f.Close()
+			os.Remove(f.Name())
+		})
+		Expect(ColorAuto.enabled(f)).To(BeFalse())
+
+		os.Setenv("NO_COLOR", "1")
+		DeferCleanup(os.Unsetenv, "NO_COLOR")
+		Expect(ColorAuto.enabled(os.Stdout)).To(BeFalse())
+		Expect(ColorAlways.enabled(os.Stdout)).To(BeTrue())
+	})
+
+	It("colorizes matching cells", func() {
+		rules := []ColorRule{
+			{Value: "Failed", Color: Red},
+			{Pattern: regexp.MustCompile(`^Run`), Color: Green},
+		}
+		Expect(cellColor("Failed", rules)).To(Equal(Red))
+		Expect(cellColor("Running", rules)).To(Equal(Green))
+		Expect(cellColor("Pending", rules)).To(BeEmpty())
+	})
+
+})
@@ -0,0 +1,393 @@
+// Copyright 2019 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package klo
+
+import (
+	"cmp"
+	"fmt"
+	"net"
+	"net/netip"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Comparator compares two sort key values, returning a negative number if a
+// sorts before b, zero if a and b sort equally, and a positive number if a
+// sorts after b.
+type Comparator func(a, b interface{}) int
+
+// comparator is a registered Comparator, together with a preparation step
+// that converts sort key values into a form cheaper to compare, such as by
+// parsing them only once up front instead of on each comparison.
+type comparator struct {
+	compare  Comparator                    // compares unprepared values.
+	prepare  func(interface{}) interface{} // prepares values.
+	prepared Comparator                    // compares prepared values.
+}
+
+// newComparator returns a comparator without any preparation step.
+func newComparator(c Comparator) *comparator {
+	return &comparator{
+		compare:  c,
+		prepare:  func(v interface{}) interface{} { return v },
+		prepared: c,
+	}
+}
+
+// parsed is a sort key value prepared by a parsing comparator.
+type parsed[T any] struct {
+	val T           // parsed value.
+	ok  bool        // value could be parsed.
+	raw interface{} // original value.
+}
+
+// parsingComparator returns a comparator that compares values by their
+// parsed values. Values that cannot be parsed sort after all parsable
+// values, and in natural order of their textual representations among
+// themselves.
+func parsingComparator[T any](parse func(interface{}) (T, bool), compare func(T, T) int) *comparator {
+	prepare := func(v interface{}) interface{} {
+		val, ok := parse(v)
+		return parsed[T]{val: val, ok: ok, raw: v}
+	}
+	prepared := func(a, b interface{}) int {
+		pa, pb := a.(parsed[T]), b.(parsed[T])
+		switch {
+		case pa.ok && pb.ok:
+			return compare(pa.val, pb.val)
+		case pa.ok:
+			return -1
+		case pb.ok:
+			return 1
+		}
+		return naturalCompare(fmt.Sprintf("%v", pa.raw), fmt.Sprintf("%v", pb.raw))
+	}
+	return &comparator{
+		compare:  func(a, b interface{}) int { return prepared(prepare(a), prepare(b)) },
+		prepare:  prepare,
+		prepared: prepared,
+	}
+}
+
+// The built-in comparators.
+var (
+	timeComparator     = parsingComparator(parseTime, time.Time.Compare)
+	durationComparator = parsingComparator(parseDuration, cmp.Compare[time.Duration])
+	ipComparator       = parsingComparator(parseIP, netip.Addr.Compare)
+	semverComparator   = parsingComparator(parseSemver, compareSemvers)
+	quantityComparator = parsingComparator(parseQuantity, cmp.Compare[float64])
+)
+
+var (
+	comparatorsMu sync.RWMutex
+	// comparators by the names sort keys reference them with.
+	comparators = map[string]*comparator{
+		"time":     timeComparator,
+		"duration": durationComparator,
+		"ip":       ipComparator,
+		"semver":   semverComparator,
+		"quantity": quantityComparator,
+	}
+	// comparators by the types of the sort key values they compare.
+	typeComparators = map[reflect.Type]*comparator{
+		reflect.TypeOf(time.Time{}):      timeComparator,
+		reflect.TypeOf(time.Duration(0)): durationComparator,
+		reflect.TypeOf(net.IP{}):         ipComparator,
+		reflect.TypeOf(netip.Addr{}):     ipComparator,
+	}
+)
+
+// RegisterComparator registers a named comparator, so that sort keys can
+// reference it by name, such as in "{.Mem}:quantity". Registering a
+// comparator with the name of an already registered comparator replaces the
+// existing one.
+func RegisterComparator(name string, c Comparator) {
+	comparatorsMu.Lock()
+	defer comparatorsMu.Unlock()
+	comparators[name] = newComparator(c)
+}
+
+// LookupComparator returns the comparator registered under the specified
+// name, or nil if there is no such comparator.
+func LookupComparator(name string) Comparator {
+	if c := lookupComparator(name); c != nil {
+		return c.compare
+	}
+	return nil
+}
+
+func lookupComparator(name string) *comparator {
+	comparatorsMu.RLock()
+	defer comparatorsMu.RUnlock()
+	return comparators[name]
+}
+
+// RegisterTypeComparator registers a comparator for sort key values of the
+// specified type, which then gets used whenever two sort key values of this
+// type need to be compared and the sort key doesn't reference a comparator
+// by name. Registering a comparator for a type with an already registered
+// comparator replaces the existing one.
+func RegisterTypeComparator(typ reflect.Type, c Comparator) {
+	comparatorsMu.Lock()
+	defer comparatorsMu.Unlock()
+	typeComparators[typ] = newComparator(c)
+}
+
+// LookupTypeComparator returns the comparator registered for the specified
+// type, or nil if there is no such comparator.
+func LookupTypeComparator(typ reflect.Type) Comparator {
+	if c := lookupTypeComparator(typ); c != nil {
+		return c.compare
+	}
+	return nil
+}
+
+func lookupTypeComparator(typ reflect.Type) *comparator {
+	comparatorsMu.RLock()
+	defer comparatorsMu.RUnlock()
+	return typeComparators[typ]
+}
+
+// naturalCompare compares two strings in natural sort order, where runs of
+// digits compare by their numeric values; it orders strings exactly as
+// sortorder.NaturalLess does, but in a single pass.
+func naturalCompare(a, b string) int {
+	ia, ib := 0, 0
+	for ia < len(a) && ib < len(b) {
+		ca, cb := a[ia], b[ib]
+		da, db := isDigit(ca), isDigit(cb)
+		switch {
+		case da != db:
+			// Digits sort before all other characters.
+			if da {
+				return -1
+			}
+			return 1
+		case !da:
+			// UTF-8 compares bytewise, so there's no need to decode runes.
+			if ca != cb {
+				return cmp.Compare(ca, cb)
+			}
+			ia++
+			ib++
+		default:
+			// Skip leading zeros, then compare the numbers first by their
+			// lengths and then digit by digit; otherwise, the number with
+			// fewer leading zeros sorts first.
+			for ; ia < len(a) && a[ia] == '0'; ia++ {
+			}
+			for ; ib < len(b) && b[ib] == '0'; ib++ {
+			}
+			nza, nzb := ia, ib
+			for ; ia < len(a) && isDigit(a[ia]); ia++ {
+			}
+			for ; ib < len(b) && isDigit(b[ib]); ib++ {
+			}
+			if c := cmp.Compare(ia-nza, ib-nzb); c != 0 {
+				return c
+			}
+			if c := strings.Compare(a[nza:ia], b[nzb:ib]); c != 0 {
+				return c
+			}
+			if c := cmp.Compare(nza, nzb); c != 0 {
+				return c
+			}
+		}
+	}
+	return cmp.Compare(len(a), len(b))
+}
+
+func isDigit(c byte) bool { return c >= '0' && c <= '9' }
+
+// CompareTime compares points in time, given either as time.Time values or
+// as RFC 3339 formatted strings.
+func CompareTime(a, b interface{}) int {
+	return timeComparator.compare(a, b)
+}
+
+func parseTime(v interface{}) (time.Time, bool) {
+	switch t := v.(type) {
+	case time.Time:
+		return t, true
+	case *time.Time:
+		if t != nil {
+			return *t, true
+		}
+	case string:
+		if tt, err := time.Parse(time.RFC3339Nano, t); err == nil {
+			return tt, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// CompareDuration compares durations, given either as time.Duration values,
+// integer nanoseconds, or as strings, such as "1h30m".
+func CompareDuration(a, b interface{}) int {
+	return durationComparator.compare(a, b)
+}
+
+func parseDuration(v interface{}) (time.Duration, bool) {
+	if s, ok := v.(string); ok {
+		d, err := time.ParseDuration(s)
+		return d, err == nil
+	}
+	val := reflect.ValueOf(v)
+	switch val.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return time.Duration(val.Int()), true
+	}
+	return 0, false
+}
+
+// CompareIP compares IP addresses, given either as net.IP or netip.Addr
+// values, or as strings. IPv4 addresses sort before IPv6 addresses, except
+// for IPv4-mapped IPv6 addresses, which sort as their IPv4 addresses.
+func CompareIP(a, b interface{}) int {
+	return ipComparator.compare(a, b)
+}
+
+func parseIP(v interface{}) (netip.Addr, bool) {
+	switch ip := v.(type) {
+	case netip.Addr:
+		return ip.Unmap(), ip.IsValid()
+	case net.IP:
+		addr, ok := netip.AddrFromSlice(ip)
+		return addr.Unmap(), ok
+	case string:
+		addr, err := netip.ParseAddr(ip)
+		return addr.Unmap(), err == nil
+	}
+	return netip.Addr{}, false
+}
+
+// CompareSemver compares semantic versions, such as "1.10.0", "v1.9.0", or
+// "2.0.0-rc.1", according to the semantic versioning precedence rules, see
+// https://semver.org. Missing minor and patch versions count as 0, and any
+// build metadata gets ignored.
+func CompareSemver(a, b interface{}) int {
+	return semverComparator.compare(a, b)
+}
+
+// semver is a parsed semantic version.
+type semver struct {
+	version    [3]uint64
+	prerelease []string
+}
+
+func parseSemver(v interface{}) (semver, bool) {
+	s, ok := v.(string)
+	if !ok {
+		return semver{}, false
+	}
+	s = strings.TrimPrefix(s, "v")
+	s, _, _ = strings.Cut(s, "+")
+	s, pre, hasPre := strings.Cut(s, "-")
+	var sv semver
+	parts := strings.Split(s, ".")
+	if len(parts) > 3 {
+		return semver{}, false
+	}
+	for idx, part := range parts {
+		n, err := strconv.ParseUint(part, 10, 64)
+		if err != nil {
+			return semver{}, false
+		}
+		sv.version[idx] = n
+	}
+	if hasPre {
+		sv.prerelease = strings.Split(pre, ".")
+	}
+	return sv, true
+}
+
+func compareSemvers(a, b semver) int {
+	for idx := range a.version {
+		if c := cmp.Compare(a.version[idx], b.version[idx]); c != 0 {
+			return c
+		}
+	}
+	// A version without pre-release identifiers has a higher precedence than
+	// the same version with pre-release identifiers.
+	switch {
+	case len(a.prerelease) == 0 && len(b.prerelease) == 0:
+		return 0
+	case len(a.prerelease) == 0:
+		return 1
+	case len(b.prerelease) == 0:
+		return -1
+	}
+	for idx := 0; idx < len(a.prerelease) && idx < len(b.prerelease); idx++ {
+		na, aerr := strconv.ParseUint(a.prerelease[idx], 10, 64)
+		nb, berr := strconv.ParseUint(b.prerelease[idx], 10, 64)
+		var c int
+		switch {
+		case aerr == nil && berr == nil:
+			c = cmp.Compare(na, nb)
+		case aerr == nil:
+			c = -1 // numeric identifiers have lower precedence.
+		case berr == nil:
+			c = 1
+		default:
+			c = strings.Compare(a.prerelease[idx], b.prerelease[idx])
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return cmp.Compare(len(a.prerelease), len(b.prerelease))
+}
+
+// CompareQuantity compares Kubernetes-style resource quantities, such as
+// "512Mi", "2Gi", "100m", or "1.5e3", given as strings or as numbers.
+func CompareQuantity(a, b interface{}) int {
+	return quantityComparator.compare(a, b)
+}
+
+// quantitySuffixes maps the binary and decimal SI suffixes of quantities to
+// their multipliers; the binary suffixes need to come first, so they take
+// precedence over the decimal suffixes they end in.
+var quantitySuffixes = []struct {
+	suffix     string
+	multiplier float64
+}{
+	{"Ki", 1 << 10}, {"Mi", 1 << 20}, {"Gi", 1 << 30},
+	{"Ti", 1 << 40}, {"Pi", 1 << 50}, {"Ei", 1 << 60},
+	{"n", 1e-9}, {"u", 1e-6}, {"m", 1e-3},
+	{"k", 1e3}, {"M", 1e6}, {"G", 1e9}, {"T", 1e12}, {"P", 1e15}, {"E", 1e18},
+}
+
+func parseQuantity(v interface{}) (float64, bool) {
+	s, ok := v.(string)
+	if !ok {
+		return numberOf(reflect.ValueOf(v))
+	}
+	multiplier := 1.0
+	for _, qs := range quantitySuffixes {
+		if strings.HasSuffix(s, qs.suffix) {
+			s = strings.TrimSuffix(s, qs.suffix)
+			multiplier = qs.multiplier
+			break
+		}
+	}
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, false
+	}
+	return n * multiplier, true
+}
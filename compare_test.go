@@ -0,0 +1,150 @@
+// Copyright 2019 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package klo
+
+import (
+	"net"
+	"net/netip"
+	"reflect"
+	"time"
+
+	"github.com/fvbommel/sortorder"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("comparators", func() {
+
+	sign := func(c int) int {
+		switch {
+		case c < 0:
+			return -1
+		case c > 0:
+			return 1
+		}
+		return 0
+	}
+
+	expectOrdered := func(c Comparator, vals ...interface{}) {
+		GinkgoHelper()
+		for idx := 0; idx < len(vals)-1; idx++ {
+			Expect(sign(c(vals[idx], vals[idx+1]))).To(Equal(-1), "%v < %v", vals[idx], vals[idx+1])
+			Expect(sign(c(vals[idx+1], vals[idx]))).To(Equal(1), "%v > %v", vals[idx+1], vals[idx])
+			Expect(c(vals[idx], vals[idx])).To(BeZero(), "%v = %v", vals[idx], vals[idx])
+		}
+	}
+
+	It("compares points in time", func() {
+		t0 := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+		t1 := t0.Add(time.Second)
+		expectOrdered(CompareTime, t0, &t1, "2019-01-01T00:00:02Z", "bar", "foo")
+		Expect(CompareTime((*time.Time)(nil), t0)).To(Equal(1))
+	})
+
+	It("compares durations", func() {
+		expectOrdered(CompareDuration, time.Millisecond, "1s", 2*time.Second, "1h30m", int64(2*time.Hour), "forever")
+	})
+
+	It("compares IP addresses", func() {
+		expectOrdered(CompareIP,
+			"9.0.0.1", net.ParseIP("10.0.0.1"), "::ffff:10.0.0.2", netip.MustParseAddr("10.0.0.10"),
+			"::1", "fe80::1", "localhost")
+		Expect(CompareIP(net.ParseIP("10.0.0.1"), "10.0.0.1")).To(BeZero())
+		Expect(CompareIP(netip.Addr{}, "10.0.0.1")).To(Equal(1))
+	})
+
+	It("compares semantic versions", func() {
+		expectOrdered(CompareSemver,
+			"0.9", "1.0.0-alpha", "1.0.0-alpha.1", "1.0.0-alpha.beta", "1.0.0-beta",
+			"1.0.0-beta.2", "1.0.0-beta.11", "1.0.0-rc.1", "v1.0.0", "1.9.0", "1.10.0",
+			"2.0.0+build.1", "1.2.3.4", "latest")
+		Expect(CompareSemver("1.0.0+a", "1.0.0+b")).To(BeZero())
+		Expect(CompareSemver("1.x", 42)).To(Equal(-1))
+	})
+
+	It("compares quantities", func() {
+		expectOrdered(CompareQuantity,
+			"100m", 1, "1.5", "1k", "1Ki", "1.5e3", "512Mi", "1G", "2Gi", "1Ti", "lots")
+		Expect(CompareQuantity("1024", "1Ki")).To(BeZero())
+	})
+
+	It("compares strings in natural order", func() {
+		strs := []string{
+			"", "0", "00", "000", "1", "01", "001", "10", "010", "2", "9", "99", "100",
+			"a", "A", "a0", "a00", "a1", "a01", "a10", "a2", "a2b", "a2b1", "a02b",
+			"ab", "b", "x9y", "x09y", "x10y", "ü", "ü1", "1ü", "9223372036854775808",
+			"18446744073709551616", "0018446744073709551616",
+		}
+		for _, a := range strs {
+			for _, b := range strs {
+				Expect(naturalCompare(a, b) < 0).To(Equal(sortorder.NaturalLess(a, b)), "%q < %q", a, b)
+				Expect(naturalCompare(a, b) == 0).To(Equal(a == b), "%q = %q", a, b)
+			}
+		}
+	})
+
+	It("registers comparators", func() {
+		Expect(LookupComparator("quantity")).NotTo(BeNil())
+		Expect(LookupComparator("nonexisting")).To(BeNil())
+		RegisterComparator("length", func(a, b interface{}) int {
+			return len(a.(string)) - len(b.(string))
+		})
+		DeferCleanup(func() {
+			comparatorsMu.Lock()
+			defer comparatorsMu.Unlock()
+			delete(comparators, "length")
+		})
+		Expect(LookupComparator("length")).NotTo(BeNil())
+
+		type tversion string
+		typ := reflect.TypeOf(tversion(""))
+		Expect(LookupTypeComparator(typ)).To(BeNil())
+		RegisterTypeComparator(typ, CompareSemver)
+		DeferCleanup(func() {
+			comparatorsMu.Lock()
+			defer comparatorsMu.Unlock()
+			delete(typeComparators, typ)
+		})
+		Expect(LookupTypeComparator(typ)).NotTo(BeNil())
+		Expect(LookupTypeComparator(reflect.TypeOf(time.Time{}))).NotTo(BeNil())
+	})
+
+	It("sorts by typed and named comparators", func() {
+		type row struct {
+			Name    string
+			Created time.Time
+			IP      net.IP
+			Mem     string
+		}
+		t0 := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+		table := func() []row {
+			return []row{
+				{Name: "foo", Created: t0.Add(time.Hour), IP: net.ParseIP("10.0.0.10"), Mem: "2Gi"},
+				{Name: "bar", Created: t0, IP: net.ParseIP("10.0.0.9"), Mem: "512Mi"},
+				{Name: "baz", Created: t0.Add(time.Minute), IP: net.ParseIP("9.0.0.1"), Mem: "1G"},
+			}
+		}
+		ccp := GoodPrinter(NewCustomColumnsPrinterFromSpec("NAME:{.Name}"))
+		PrinterPass(GoodPrinter(NewSortingPrinter("{.Created}", ccp)), table(), "NAME\nbar\nbaz\nfoo\n")
+		PrinterPass(GoodPrinter(NewSortingPrinter("{.IP}", ccp)), table(), "NAME\nbaz\nbar\nfoo\n")
+		PrinterPass(GoodPrinter(NewSortingPrinter("{.Mem}", ccp)), table(), "NAME\nbaz\nfoo\nbar\n")
+		PrinterPass(GoodPrinter(NewSortingPrinter("{.Mem}:quantity", ccp)), table(), "NAME\nbar\nbaz\nfoo\n")
+		PrinterPass(GoodPrinter(NewSortingPrinter("-Mem:quantity,{.Name}", ccp)), table(), "NAME\nfoo\nbaz\nbar\n")
+		BadPrinter(NewSortingPrinter("{.Mem}:nonexisting", ccp))
+		Expect(splitTopLevel("{.a[1:2]}:{':'}:semver", ':')).To(Equal(
+			[]string{"{.a[1:2]}", "{':'}", "semver"}))
+	})
+
+})
@@ -0,0 +1,123 @@
+// Copyright 2019 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package klo
+
+import (
+	"bytes"
+	"slices"
+	"sync"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("concurrent printing", func() {
+
+	type proc struct {
+		PID     int
+		Name    string
+		Cmdline []string
+	}
+
+	procs := []proc{
+		{PID: 42, Name: "foo", Cmdline: []string{"/bin/foo", "-v"}},
+		{PID: 1, Name: "init", Cmdline: []string{"/sbin/init"}},
+		{PID: 666, Name: "bar", Cmdline: []string{"/bin/bar"}},
+	}
+
+	// expectConcurrentlySafe prints the same value using the same printer
+	// from multiple goroutines at the same time, expecting the same output
+	// as when printing it only once. Run with "go test -race" to also catch
+	// any data races.
+	expectConcurrentlySafe := func(p ValuePrinter, v func() interface{}) {
+		GinkgoHelper()
+		var expected bytes.Buffer
+		Expect(p.Fprint(&expected, v())).To(Succeed())
+		Expect(expected.Len()).NotTo(BeZero())
+
+		const goroutines = 8
+		const iterations = 20
+		var wg sync.WaitGroup
+		outputs := make([][]string, goroutines)
+		for g := range goroutines {
+			wg.Add(1)
+			go func() {
+				defer GinkgoRecover()
+				defer wg.Done()
+				for range iterations {
+					var out bytes.Buffer
+					Expect(p.Fprint(&out, v())).To(Succeed())
+					outputs[g] = append(outputs[g], out.String())
+				}
+			}()
+		}
+		wg.Wait()
+		for _, outs := range outputs {
+			for _, out := range outs {
+				Expect(out).To(Equal(expected.String()))
+			}
+		}
+	}
+
+	values := func() interface{} { return procs }
+
+	It("prints custom columns tables", func() {
+		ccp := GoodPrinter(NewCustomColumnsPrinterFromSpec(
+			"PID:{.PID},NAME:{.Name},CMD:{.Cmdline[0]},ARGS:{.Cmdline[1:]}"))
+		expectConcurrentlySafe(ccp, values)
+		expectConcurrentlySafe(ccp, func() interface{} { return slices.Values(procs) })
+		ccp.(*CustomColumnsPrinter).Renderer = &MarkdownRenderer{}
+		expectConcurrentlySafe(ccp, values)
+	})
+
+	It("prints JSONPath results", func() {
+		expectConcurrentlySafe(GoodPrinter(NewJSONPathPrinter(
+			"{range [*]}{.Name}{' '}{.Cmdline[0]}{'\\n'}{end}")), values)
+	})
+
+	It("prints JSON, YAML, and Go templates", func() {
+		expectConcurrentlySafe(GoodPrinter(NewJSONPrinter()), values)
+		expectConcurrentlySafe(GoodPrinter(NewYAMLPrinter()), values)
+		expectConcurrentlySafe(GoodPrinter(NewGoTemplatePrinter(
+			"{{range .}}{{.Name}} {{index .Cmdline 0}}\n{{end}}")), values)
+	})
+
+	It("sorts, filters, and limits", func() {
+		ccp := GoodPrinter(NewCustomColumnsPrinterFromSpec("PID:{.PID},CMD:{.Cmdline[0]}"))
+		lp := GoodPrinter(NewLimitingPrinter(2, ccp))
+		fp := GoodPrinter(NewFilteringPrinter([]string{"{.Cmdline[0]}=~^/bin/"}, lp))
+		sp := GoodPrinter(NewSortingPrinter("-{.Cmdline[0]},PID", fp))
+		expectConcurrentlySafe(sp, values)
+		expectConcurrentlySafe(GoodPrinter(NewSortingPrinter("{.Cmdline[0]}", &JSONPrinter{})), values)
+		expectConcurrentlySafe(GoodPrinter(NewSortingPrinter(
+			"{range .Cmdline[*]}{@}{end}", ccp)), values)
+	})
+
+	It("prints watch events", func() {
+		ccp := GoodPrinter(NewCustomColumnsPrinterFromSpec("PID:{.PID},NAME:{.Name}"))
+		wp := GoodPrinter(NewWatchPrinter("{.Cmdline[0]}", ccp)).(*WatchPrinter)
+		events := func() interface{} {
+			return []Event{
+				{Type: Added, Object: procs[0]},
+				{Type: Modified, Object: procs[1]},
+				{Type: Deleted, Object: procs[0]},
+			}
+		}
+		expectConcurrentlySafe(wp, events)
+		wp.Redraw = true
+		expectConcurrentlySafe(wp, events)
+	})
+
+})
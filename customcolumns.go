@@ -35,18 +35,59 @@ type CustomColumnsPrinter struct {
 	HideHeaders bool
 	// Padding between columns
 	Padding int
+	// Write rows as soon as the column widths are known from the declared
+	// column widths and the first SampleRows rows, instead of buffering the
+	// whole table. Streams of items are always written this way if any
+	// columns have declared widths or SampleRows is set.
+	Streaming bool
+	// Number of rows to sample for calculating the column widths when
+	// streaming, before writing any rows; see also Column.Width.
+	SampleRows int
+	// How to handle cells overflowing their column widths when streaming.
+	Overflow Overflow
+	// Color mode, defaulting to coloring only terminal output.
+	Color ColorMode
+	// Optional color for the column headers, such as Bold.
+	HeaderColor Color
+	// Optional renderer for rendering the evaluated table instead of
+	// writing aligned text, such as a MarkdownRenderer or CSVRenderer.
+	Renderer TableRenderer
+	// Number of goroutines evaluating batches of rows in parallel, with the
+	// rows still getting printed in their original order; 0 or 1 evaluates
+	// rows one after another. Rows of streams get printed batch by batch.
+	Workers int
 }
 
 // Column stores the header text and the JSONPath for fetching column values.
 // In addition, it features a column name, which is used to identify a
-// specific column when reporting errors.
+// specific column when reporting errors. Optional color rules color the
+// column's cells depending on their texts. Cells wider than an optional
+// maximum display width get truncated. An optional formatter renders the
+// column's values into their cell texts.
 type Column struct {
-	Name     string             // Column name, for error reporting.
-	Header   string             // Column header text.
-	Template *jsonpath.JSONPath // Compiled JSONPath expression.
-	Raw      string             // Original JSONPath expression.
+	Name      string             // Column name, for error reporting.
+	Header    string             // Column header text.
+	Template  *jsonpath.JSONPath // Compiled JSONPath expression.
+	Raw       string             // Original JSONPath expression.
+	Colors    []ColorRule        // Optional rules for coloring cells.
+	MaxWidth  int                // Optional maximum cell display width; 0 is unlimited.
+	Width     int                // Optional minimum cell display width; 0 is undeclared.
+	Align     Alignment          // Alignment of cells; defaults to left-aligned.
+	Formatter Formatter          // Optional formatter for the column values.
+	isKey     bool               // Column shows map keys instead of values.
+	fields    *fieldPath         // Faster evaluation of simple field paths, if any.
+	ranged    *rangedExpr        // Expression with range blocks, if any.
 }
 
+// Alignment specifies how to align the cells of a column.
+type Alignment int
+
+// Supported column cell alignments.
+const (
+	AlignLeft  Alignment = iota // left-aligned column cells.
+	AlignRight                  // right-aligned column cells.
+)
+
 // NewCustomColumnsPrinterFromSpec returns a new custom columns printer for the
 // given specification. This specification is in form of a string consisting of
 // a series of <column-header-name>:<json-path-expr> elements, separated by ",".
@@ -124,75 +165,103 @@ func NewCustomColumnsPrinterFromTemplate(tr io.Reader) (ValuePrinter, error) {
 
 // Fprint prints the value v in a neatly formatted table according to the
 // custom-column spec or template given when creating this custom-columns
-// printer. The table is then written to the specified writer. If this writer
-// is already a tabwriter, then it is the caller's responsibility to flush the
-// tabwriter when it's the right point to do so.
+// printer. Collections, such as slices, arrays, maps, and Kubernetes-style
+// List objects, get printed with one row per item, even when passed by
+// pointer. Streams of items, that is, channels, iter.Seq, and iter.Seq2,
+// get printed with one row per item too; if any columns have a declared
+// Width, or SampleRows is set, then the rows get written as the items
+// arrive. Setting Streaming writes the rows of all values without buffering
+// the whole table. Other values get printed as a single row. The table is
+// then written to the specified writer. If this writer is already a
+// tabwriter, then it is the caller's responsibility to flush the tabwriter
+// when it's the right point to do so. Please note that tabwriters misalign
+// colored cells, as they count ANSI escape sequences as visible text. If a
+// Renderer has been set, then the whole table gets evaluated first and then
+// rendered by this Renderer instead.
 func (p *CustomColumnsPrinter) Fprint(w io.Writer, v interface{}) error {
-	// If the writer given isn't a tabwriter, let's wrap it into one! And only
-	// then ensure that the tabbed table gets flushed, so the column widths
-	// get calculated and the columns properly aligned. If the caller gave us
-	// a tabwriter, then it is her/his responsibility to flush the tabwriter
-	// table when necessary.
-	if _, ok := w.(*tabwriter.Writer); !ok {
-		tw := tabwriter.NewWriter(w, 5, 8, p.Padding, ' ', 0)
-		defer tw.Flush()
-		w = tw
-	}
-	// Print column headers ... but only if not hidden...
-	if !p.HideHeaders {
-		headers := make([]string, len(p.Columns))
-		for idx, column := range p.Columns {
-			headers[idx] = column.Header
+	if p.Renderer != nil {
+		t := Columns(p.Columns).table(v, p.Workers)
+		if err := t.Err(); err != nil {
+			return err
+		}
+		if p.HideHeaders {
+			t.Headers = nil
 		}
-		fmt.Fprintln(w, strings.Join(headers, "\t"))
+		return p.Renderer.Render(w, t)
 	}
-	// Print value(s)...
-	if v != nil {
-		if reflect.TypeOf(v).Kind() == reflect.Slice {
-			sl := reflect.ValueOf(v)
-			for idx := 0; idx < sl.Len(); idx++ {
-				// Work on a single row and now find the results of all columns
-				// for the current object...
-				rowval := sl.Index(idx).Interface()
-				if rv, ok := rowval.(reflect.Value); ok {
-					rowval = rv.Interface()
-				}
-				if err := p.printrow(w, rowval); err != nil {
-					return err
-				}
-			}
+	// If the writer given isn't a tabwriter, let's write the table rows into
+	// our own aligning row writer, which correctly handles ANSI escape
+	// sequences. And only then ensure that the table gets flushed, so the
+	// column widths get calculated and the columns properly aligned. If the
+	// caller gave us a tabwriter, then it is her/his responsibility to flush
+	// the tabwriter table when necessary.
+	var rw rowWriter
+	if tw, ok := w.(*tabwriter.Writer); ok {
+		rw = &tabRowWriter{w: tw}
+	} else {
+		layout := p.layout()
+		// Streams of items get written as they arrive, as long as we know
+		// how to calculate the column widths up front. Otherwise, we need to
+		// buffer all rows in order to align the columns, unless asked to
+		// stream anyway.
+		if _, _, ok := stream(v); p.Streaming || ok && (layout.declared() || p.SampleRows > 0) {
+			rw = p.streamingRowWriter(w, layout)
 		} else {
-			if rv, ok := v.(reflect.Value); ok {
-				v = rv.Interface()
-			}
-			return p.printrow(w, v)
+			rw = newAlignedRowWriter(w, layout)
 		}
 	}
-	return nil
+	err := p.fprint(rw, v, p.Color.enabled(w))
+	if ferr := rw.flush(); err == nil {
+		err = ferr
+	}
+	return err
+}
+
+// streamingRowWriter returns a new streaming row writer for this printer,
+// sampling the configured number of rows (plus headers) for column widths.
+func (p *CustomColumnsPrinter) streamingRowWriter(w io.Writer, layout columnLayout) *streamingRowWriter {
+	samples := p.SampleRows
+	if !p.HideHeaders {
+		samples++
+	}
+	return newStreamingRowWriter(w, layout, samples, p.Overflow)
+}
+
+// layout returns the layout of this printer's columns.
+func (p *CustomColumnsPrinter) layout() columnLayout {
+	return layoutOf(p.Columns, p.Padding)
+}
+
+// fprint writes the table rows for the value v to the specified row writer.
+func (p *CustomColumnsPrinter) fprint(rw rowWriter, v interface{}, colored bool) error {
+	if err := p.printheaders(rw, colored); err != nil {
+		return err
+	}
+	// Print value(s)...
+	return Columns(p.Columns).rows(v, p.Workers, func(row Row) error {
+		return p.writerow(rw, row, colored)
+	})
+}
+
+// printheaders prints the column headers ... but only if not hidden.
+func (p *CustomColumnsPrinter) printheaders(rw rowWriter, colored bool) error {
+	if p.HideHeaders {
+		return nil
+	}
+	return rw.writeRow(headerTexts(Columns(p.Columns).Headers(), p.HeaderColor, colored))
 }
 
 // printrow prints a single row, that is, a single row object.
-func (p *CustomColumnsPrinter) printrow(w io.Writer, rowval interface{}) error {
-	rowvals := make([]string, len(p.Columns))
-	for cidx, col := range p.Columns {
-		// Calculate the result of a this column for the current row.
-		res, err := col.Template.FindResults(rowval)
-		if err != nil {
-			return err
-		}
-		// Depending on the JSONPath expression, the result for this
-		// column might consist of multiple values, or even none at
-		// all.
-		if len(res) == 0 || len(res[0]) == 0 {
-			rowvals[cidx] = "<none>"
-		} else {
-			rowvals[cidx] = stringFromJSONExprResult(res, ", ")
-		}
+func (p *CustomColumnsPrinter) printrow(rw rowWriter, rowval interface{}, colored bool) error {
+	return p.writerow(rw, Columns(p.Columns).Row(rowval), colored)
+}
+
+// writerow writes a single evaluated row, unless evaluating the row failed.
+func (p *CustomColumnsPrinter) writerow(rw rowWriter, row Row, colored bool) error {
+	if err := row.Err(); err != nil {
+		return err
 	}
-	// Print this column by printing all columns' values, separated by god'ol
-	// horizontal tab control chars.
-	_, err := fmt.Fprintln(w, strings.Join(rowvals, "\t"))
-	return err
+	return rw.writeRow(row.texts(p.Columns, colored))
 }
 
 // Stringifies a JSONPath expression result.
@@ -222,16 +291,42 @@ var jsonPathRegexp = regexp.MustCompile(`^\{\.?([^{}]+)\}$|^\.?([^{}]+)$`)
 //   * {x.y.z} ... without leading ".", but at least curly braces.
 //   * .x.y.z ... without curly braces.
 //   * {.x.y.z} ... and finally as "standard".
-// Additionally, the empty expression "" also gets accepted.
+// Additionally, the empty expression "" also gets accepted, as well as the
+// "{$key}" (or "$key") pseudo expression referencing the keys of map entries.
 func (c *Column) SetExpression(exp string) error {
+	c.isKey = false
+	c.fields = nil
+	c.ranged = nil
 	if exp == "" {
 		c.Template = jsonpath.New(c.Name)
 		return nil
 	}
 	c.Raw = exp
+	if exp == keyExpr || "{"+exp+"}" == keyExpr {
+		c.isKey = true
+		c.Template = jsonpath.New(c.Name)
+		return nil
+	}
+	exp, err := relaxedExpression(exp)
+	if err != nil {
+		return err
+	}
+	c.Template = jsonpath.New(c.Name).AllowMissingKeys(true)
+	if err := c.Template.Parse(exp); err != nil {
+		return err
+	}
+	c.fields = newFieldPath(c.Template, exp)
+	c.ranged = newRangedExpr(c.Template, c.Name, exp)
+	return nil
+}
+
+// relaxedExpression returns the normalized JSONPath expression for the more
+// relaxed JSONPath expression syntax accepted by kubectl for custom columns,
+// see also Column.SetExpression.
+func relaxedExpression(exp string) (string, error) {
 	sm := jsonPathRegexp.FindStringSubmatch(exp)
 	if sm == nil {
-		return fmt.Errorf("unexpected path string, expected a 'name1.name2' or '.name1.name2' or '{name1.name2}' or '{.name1.name2}'")
+		return "", fmt.Errorf("unexpected path string, expected a 'name1.name2' or '.name1.name2' or '{name1.name2}' or '{.name1.name2}'")
 	}
 	// Pick up the one expression which matched; in any case it'll be without
 	// any enclosing curly braces, and without any leading ".". Then, turn it
@@ -241,6 +336,5 @@ func (c *Column) SetExpression(exp string) error {
 	} else {
 		exp = sm[2]
 	}
-	c.Template = jsonpath.New(c.Name).AllowMissingKeys(true)
-	return c.Template.Parse(fmt.Sprintf("{.%s}", exp))
+	return fmt.Sprintf("{.%s}", exp), nil
 }
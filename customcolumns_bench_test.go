@@ -0,0 +1,65 @@
+// Copyright 2019 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package klo
+
+import (
+	"io"
+	"runtime"
+	"testing"
+)
+
+func benchmarkCustomColumnsPrinter(b *testing.B, fieldPaths bool, workers int) {
+	procs := benchmarkProcesses(20000)
+	p, err := NewCustomColumnsPrinterFromSpec("PID:{.PID},NAME:{.Name},MEM:{.Mem},CMD:{.Cmdline[0]}")
+	if err != nil {
+		b.Fatal(err)
+	}
+	p.(*CustomColumnsPrinter).Workers = workers
+	if !fieldPaths {
+		for _, column := range p.(*CustomColumnsPrinter).Columns {
+			column.fields = nil
+		}
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := p.Fprint(io.Discard, procs); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkCustomColumnsPrinter(b *testing.B) {
+	benchmarkCustomColumnsPrinter(b, true, 0)
+}
+
+func BenchmarkCustomColumnsPrinterJSONPath(b *testing.B) {
+	benchmarkCustomColumnsPrinter(b, false, 0)
+}
+
+func BenchmarkCustomColumnsPrinterWorkers(b *testing.B) {
+	benchmarkCustomColumnsPrinter(b, true, 4)
+}
+
+func BenchmarkCustomColumnsPrinterJSONPathWorkers(b *testing.B) {
+	benchmarkCustomColumnsPrinter(b, false, 4)
+}
+
+// BenchmarkCustomColumnsPrinterGOMAXPROCS evaluates rows using as many
+// workers as GOMAXPROCS, so running it with "-cpu 1,2,4" shows the speedup
+// of parallel evaluation compared to evaluating rows one after another.
+func BenchmarkCustomColumnsPrinterGOMAXPROCS(b *testing.B) {
+	benchmarkCustomColumnsPrinter(b, false, runtime.GOMAXPROCS(0))
+}
@@ -15,7 +15,11 @@
 package klo
 
 import (
+	"bytes"
+	"math"
+	"slices"
 	"strings"
+	"text/tabwriter"
 
 	"k8s.io/client-go/util/jsonpath"
 
@@ -45,6 +49,8 @@ var _ = Describe("custom columns printer", func() {
 			t.PASS{"relaxed . spec", c.SetExpression(".foo")},
 			t.PASS{"relaxed {} spec", c.SetExpression("{foo}")},
 			t.PASS{"correct spec", c.SetExpression("{.foo}")},
+			t.PASS{"map key spec", c.SetExpression("{$key}")},
+			t.PASS{"relaxed map key spec", c.SetExpression("$key")},
 			t.FAIL{"incomplete { spec", c.SetExpression("{foo")},
 			t.FAIL{"incomplete [ spec", c.SetExpression("foo[0")},
 		}) //nolint:composites
@@ -139,4 +145,230 @@ verylongfoo   bar!   <none>
 `)
 	})
 
+	It("colors cells and keeps them aligned", func() {
+		type tstatus struct {
+			Name   string
+			Status string
+		}
+		p := GoodPrinter(NewCustomColumnsPrinterFromSpec("NAME:Name,STATUS:Status,X:Name"))
+		ccp := p.(*CustomColumnsPrinter)
+		ccp.Color = ColorAlways
+		ccp.HeaderColor = Bold
+		ccp.Columns[1].Colors = []ColorRule{
+			{Value: "Failed", Color: Red},
+			{Value: "Running", Color: Green},
+		}
+		PrinterPass(p, []tstatus{
+			{Name: "foo", Status: "Running"},
+			{Name: "verylongbar", Status: "Failed"},
+			{Name: "baz", Status: "Pending"},
+		}, "\x1b[1mNAME\x1b[0m        \x1b[1mSTATUS\x1b[0m  \x1b[1mX\x1b[0m\n"+
+			"foo         \x1b[32mRunning\x1b[0m foo\n"+
+			"verylongbar \x1b[31mFailed\x1b[0m  verylongbar\n"+
+			"baz         Pending baz\n")
+
+		ccp.Color = ColorNever
+		PrinterPass(p, []tstatus{{Name: "foo", Status: "Failed"}}, `NAME STATUS X
+foo  Failed foo
+`)
+	})
+
+	It("leaves flushing caller's tabwriter to the caller", func() {
+		p := GoodPrinter(NewCustomColumnsPrinterFromSpec("FOO:Foo,BAR:Bar"))
+		var out strings.Builder
+		tw := tabwriter.NewWriter(&out, 5, 8, 1, ' ', 0)
+		Expect(p.Fprint(tw, foo)).To(Succeed())
+		Expect(out.String()).To(BeEmpty())
+		Expect(tw.Flush()).To(Succeed())
+		Expect(out.String()).To(Equal(`FOO         BAR
+verylongfoo bar!
+`))
+	})
+
+	It("aligns and truncates wide characters", func() {
+		p := GoodPrinter(NewCustomColumnsPrinterFromSpec("FOO:Foo,BAR:Bar,BAZ:Foo"))
+		rows := []tfoo{
+			{Foo: "日本語", Bar: "🚀"},
+			{Foo: "foo", Bar: "e\u0301te\u0301"},
+		}
+		PrinterPass(p, rows, "FOO    BAR  BAZ\n"+
+			"日本語 🚀   日本語\n"+
+			"foo    e\u0301te\u0301  foo\n")
+		p.(*CustomColumnsPrinter).Columns[0].MaxWidth = 4
+		PrinterPass(p, rows, "FOO  BAR  BAZ\n"+
+			"日…  🚀   日本語\n"+
+			"foo  e\u0301te\u0301  foo\n")
+	})
+
+	It("prints maps in sorted key order", func() {
+		p := GoodPrinter(NewCustomColumnsPrinterFromSpec("ID:{$key},FOO:Foo,BAR:Bar"))
+		registry := map[string]tfoo{
+			"id-10": {Foo: "foo10", Bar: "bar10"},
+			"id-9":  {Foo: "foo9", Bar: "bar9"},
+			"id-1":  {Foo: "foo1"},
+		}
+		PrinterPass(p, registry, `ID    FOO   BAR
+id-1  foo1  
+id-9  foo9  bar9
+id-10 foo10 bar10
+`)
+		PrinterPass(p, map[string]tfoo{}, `ID   FOO  BAR
+`)
+		PrinterPass(p, foo, `ID     FOO         BAR
+<none> verylongfoo bar!
+`)
+		PrinterPass(p, []MapEntry{{Key: 42, Value: tfoo{Foo: "foo"}}}, `ID   FOO  BAR
+42   foo  
+`)
+	})
+
+	It("prints maps with NaN keys", func() {
+		p := GoodPrinter(NewCustomColumnsPrinterFromSpec("K:{$key},FOO:Foo"))
+		PrinterPass(p, map[float64]tfoo{
+			math.NaN(): {Foo: "b"},
+			1:          {Foo: "c"},
+			math.NaN(): {Foo: "a"},
+		}, `K    FOO
+NaN  a
+NaN  b
+1    c
+`)
+	})
+
+	It("prints streams of items", func() {
+		p := GoodPrinter(NewCustomColumnsPrinterFromSpec("FOO:Foo,BAR:Bar"))
+		items := []tfoo{{Foo: "foo", Bar: "bar"}, {Foo: "verylongfoo", Bar: "bar!"}}
+		expected := `FOO         BAR
+foo         bar
+verylongfoo bar!
+`
+		PrinterPass(p, slices.Values(items), expected)
+		ch := make(chan tfoo, 2)
+		ch <- items[0]
+		ch <- items[1]
+		close(ch)
+		PrinterPass(p, ch, expected)
+		PrinterPass(p, slices.All(items), expected)
+
+		p.(*CustomColumnsPrinter).Columns[0].Template = jsonpath.New("zero")
+		PrinterFail(p, slices.Values(items))
+	})
+
+	It("writes stream rows as they arrive", func() {
+		p := GoodPrinter(NewCustomColumnsPrinterFromSpec("FOO:Foo,BAR:Bar"))
+		ccp := p.(*CustomColumnsPrinter)
+		var out bytes.Buffer
+		// rows returns an iterator that checks the table output so far
+		// before yielding the next row.
+		rows := func(expected ...string) func(func(tfoo) bool) {
+			return func(yield func(tfoo) bool) {
+				for idx, foo := range []tfoo{{Foo: "foo", Bar: "bar"}, {Foo: "verylongfoo", Bar: "bar!"}} {
+					Expect(out.String()).To(Equal(expected[idx]))
+					if !yield(foo) {
+						return
+					}
+				}
+			}
+		}
+
+		ccp.Columns[0].Width = 4
+		Expect(p.Fprint(&out, rows("FOO  BAR\n", "FOO  BAR\nfoo  bar\n"))).To(Succeed())
+		Expect(out.String()).To(Equal(`FOO  BAR
+foo  bar
+verylongfoo bar!
+`))
+
+		out.Reset()
+		ccp.Columns[0].Width = 0
+		ccp.SampleRows = 1
+		Expect(p.Fprint(&out, rows("", "FOO  BAR\nfoo  bar\n"))).To(Succeed())
+
+		out.Reset()
+		ccp.SampleRows = 2
+		Expect(p.Fprint(&out, rows("", ""))).To(Succeed())
+		Expect(out.String()).To(Equal(`FOO         BAR
+foo         bar
+verylongfoo bar!
+`))
+
+		out.Reset()
+		ccp.SampleRows = 5
+		ccp.HideHeaders = true
+		Expect(p.Fprint(&out, rows("", ""))).To(Succeed())
+		Expect(out.String()).To(Equal(`foo         bar
+verylongfoo bar!
+`))
+
+		out.Reset()
+		Expect(p.Fprint(&failingWriter{}, rows("", ""))).NotTo(Succeed())
+		ccp.SampleRows = 1
+		Expect(p.Fprint(&failingWriter{n: 1}, rows("", ""))).NotTo(Succeed())
+	})
+
+	It("streams tables without buffering", func() {
+		p := GoodPrinter(NewCustomColumnsPrinterFromSpec("FOO:Foo,BAR:Bar,BAZ:Foo"))
+		ccp := p.(*CustomColumnsPrinter)
+		ccp.Streaming = true
+		rows := []tfoo{
+			{Foo: "foo", Bar: "bar"},
+			{Foo: "verylongfoo", Bar: "bar!"},
+			{Foo: "foo", Bar: "bar"},
+		}
+		var out writes
+		Expect(p.Fprint(&out, rows)).To(Succeed())
+		Expect(out).To(Equal(writes{
+			"FOO  BAR  BAZ\n",
+			"foo  bar  foo\n",
+			"verylongfoo bar! verylongfoo\n",
+			"foo  bar  foo\n",
+		}))
+
+		ccp.Overflow = OverflowTruncate
+		PrinterPass(p, rows, `FOO  BAR  BAZ
+foo  bar  foo
+ver… bar! verylongfoo
+foo  bar  foo
+`)
+
+		ccp.Overflow = OverflowWiden
+		PrinterPass(p, rows, `FOO  BAR  BAZ
+foo  bar  foo
+verylongfoo bar! verylongfoo
+foo         bar  foo
+`)
+
+		ccp.Overflow = OverflowTruncate
+		ccp.SampleRows = 1
+		ccp.Columns[1].Width = 6
+		PrinterPass(p, rows, `FOO  BAR    BAZ
+foo  bar    foo
+ver… bar!   verylongfoo
+foo  bar    foo
+`)
+
+		ccp.SampleRows = 2
+		PrinterPass(p, rows, `FOO         BAR    BAZ
+foo         bar    foo
+verylongfoo bar!   verylongfoo
+foo         bar    foo
+`)
+
+		ccp.SampleRows = 0
+		ccp.Columns[1].Width = 0
+		ccp.Color = ColorAlways
+		ccp.Columns[0].Colors = []ColorRule{{Value: "verylongfoo", Color: Red}}
+		PrinterPass(p, rows, "FOO  BAR  BAZ\n"+
+			"foo  bar  foo\n"+
+			"\x1b[31mver…\x1b[0m bar! verylongfoo\n"+
+			"foo  bar  foo\n")
+	})
+
 })
+
+// writes records the individual writes to it.
+type writes []string
+
+func (w *writes) Write(p []byte) (int, error) {
+	*w = append(*w, string(p))
+	return len(p), nil
+}
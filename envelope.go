@@ -0,0 +1,103 @@
+// Copyright 2019 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package klo
+
+import (
+	"reflect"
+)
+
+// ListEnvelope specifies the Kubernetes-style List object wrapping slices of
+// items in JSON and YAML output, similar to kubectl wrapping multiple objects
+// as {"apiVersion":"v1","kind":"List","items":[...],"metadata":{}}. When
+// limiting items using a LimitingPrinter, the metadata tells the number of
+// dropped items as "remainingItemCount".
+type ListEnvelope struct {
+	APIVersion string // API version of the List object; defaults to "v1".
+	Kind       string // Kind of the List object; defaults to "List".
+}
+
+// envelope is the List object wrapping items, with its fields in the same
+// order as kubectl prints them.
+type envelope struct {
+	APIVersion string       `json:"apiVersion"`
+	Kind       string       `json:"kind"`
+	Items      interface{}  `json:"items"`
+	Metadata   envelopeMeta `json:"metadata"`
+}
+
+// envelopeMeta is the metadata of the List object wrapping items.
+type envelopeMeta struct {
+	// Number of items dropped by a LimitingPrinter, if any.
+	RemainingItemCount *int64 `json:"remainingItemCount,omitempty"`
+}
+
+// wrap returns the List object wrapping the specified items.
+func (e *ListEnvelope) wrap(items interface{}) *envelope {
+	env := &envelope{APIVersion: e.APIVersion, Kind: e.Kind, Items: items}
+	if env.APIVersion == "" {
+		env.APIVersion = "v1"
+	}
+	if env.Kind == "" {
+		env.Kind = "List"
+	}
+	return env
+}
+
+// listEnvelope returns the List object envelope of the JSON or YAML printer
+// at the end of a chain of printers, or nil if the chain doesn't end in a
+// printer wrapping items in List objects.
+func listEnvelope(p ValuePrinter) *ListEnvelope {
+	for {
+		switch cp := p.(type) {
+		case *JSONPrinter:
+			if cp.Lines {
+				return nil
+			}
+			return cp.List
+		case *YAMLPrinter:
+			if cp.Documents {
+				return nil
+			}
+			return cp.List
+		case *SortingPrinter:
+			p = cp.ChainedPrinter
+		case *FilteringPrinter:
+			p = cp.ChainedPrinter
+		case *LimitingPrinter:
+			p = cp.ChainedPrinter
+		default:
+			return nil
+		}
+	}
+}
+
+// wrapSlice returns the List object wrapping the value v if v is a slice or
+// an array, otherwise v itself. Nil slices get wrapped as empty item lists.
+// Byte slices, such as []byte and json.RawMessage, don't get wrapped, as they
+// are encoded as single values instead of as arrays.
+func (e *ListEnvelope) wrapSlice(v interface{}) interface{} {
+	if e == nil {
+		return v
+	}
+	val := indirect(valueOf(v))
+	kind := val.Kind()
+	if kind != reflect.Slice && kind != reflect.Array || isByteSlice(val) {
+		return v
+	}
+	if val.Kind() == reflect.Slice && val.IsNil() {
+		val = reflect.MakeSlice(val.Type(), 0, 0)
+	}
+	return e.wrap(val.Interface())
+}
@@ -0,0 +1,134 @@
+// Copyright 2019 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package klo
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+
+	"k8s.io/client-go/util/jsonpath"
+)
+
+// fieldPathRegexp matches simple JSONPath expressions consisting only of
+// field names, such as "{.metadata.name}".
+var fieldPathRegexp = regexp.MustCompile(`^\{(\.[A-Za-z_][A-Za-z0-9_]*)+\}$`)
+
+// fieldPath is a simple JSONPath expression consisting only of field names,
+// such as "{.A.B.C}". Instead of walking values using the full JSONPath
+// machinery, field paths get evaluated using struct field indices cached per
+// struct type, yet with the same results as the JSONPath expression they
+// were compiled from.
+type fieldPath struct {
+	template *jsonpath.JSONPath // JSONPath expression compiled from the same expression.
+	names    []string           // field names (or map keys) of the path.
+	indices  sync.Map           // cached struct field indices by fieldStep.
+}
+
+// fieldStep identifies a step of a field path on a particular struct type.
+type fieldStep struct {
+	step int
+	typ  reflect.Type
+}
+
+// newFieldPath returns the field path for the specified JSONPath expression,
+// or nil if the expression isn't a simple field path. The template must have
+// been compiled from the same expression; field paths only evaluate values
+// as long as they are used together with this template.
+func newFieldPath(template *jsonpath.JSONPath, expr string) *fieldPath {
+	if !fieldPathRegexp.MatchString(expr) {
+		return nil
+	}
+	return &fieldPath{
+		template: template,
+		names:    strings.Split(expr[2:len(expr)-1], "."),
+	}
+}
+
+// find evaluates this field path on the value v, returning the value found
+// and true. Otherwise, if the path doesn't lead to any value, it returns
+// false, leaving it to the JSONPath template to handle (or report) missing
+// values.
+func (fp *fieldPath) find(v interface{}) (reflect.Value, bool) {
+	val := reflect.ValueOf(v)
+	for step, name := range fp.names {
+		val = indirect(val)
+		switch val.Kind() {
+		case reflect.Struct:
+			index := fp.fieldIndex(step, val.Type())
+			if index == nil {
+				return reflect.Value{}, false
+			}
+			var err error
+			if val, err = val.FieldByIndexErr(index); err != nil {
+				return reflect.Value{}, false
+			}
+		case reflect.Map:
+			key := reflect.ValueOf(name)
+			if !key.CanConvert(val.Type().Key()) {
+				return reflect.Value{}, false
+			}
+			if val = val.MapIndex(key.Convert(val.Type().Key())); !val.IsValid() {
+				return reflect.Value{}, false
+			}
+		default:
+			return reflect.Value{}, false
+		}
+	}
+	return val, true
+}
+
+// fieldIndex returns the (cached) index of the struct field for the
+// specified step of this field path in the struct type typ, or nil if
+// there is no such field.
+func (fp *fieldPath) fieldIndex(step int, typ reflect.Type) []int {
+	key := fieldStep{step: step, typ: typ}
+	if index, ok := fp.indices.Load(key); ok {
+		return index.([]int)
+	}
+	index := structFieldIndex(typ, fp.names[step])
+	fp.indices.Store(key, index)
+	return index
+}
+
+// structFieldIndex returns the index of the struct field with the specified
+// name in the struct type typ, or nil if there is no such field. It looks up
+// fields exactly as JSONPath does: first by their JSON names, then in the
+// last field without a JSON name if that field is a struct, and finally by
+// their Go field names.
+func structFieldIndex(typ reflect.Type, name string) []int {
+	inline := -1
+	for idx := 0; idx < typ.NumField(); idx++ {
+		jsonName, _, _ := strings.Cut(typ.Field(idx).Tag.Get("json"), ",")
+		if jsonName == name {
+			return []int{idx}
+		}
+		if jsonName == "" {
+			inline = idx
+		}
+	}
+	if inline >= 0 {
+		if ftyp := typ.Field(inline).Type; ftyp.Kind() == reflect.Struct {
+			if index := structFieldIndex(ftyp, name); index != nil {
+				return append([]int{inline}, index...)
+			}
+		}
+	}
+	if field, ok := typ.FieldByName(name); ok {
+		return field.Index
+	}
+	return nil
+}
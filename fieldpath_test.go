@@ -0,0 +1,139 @@
+// Copyright 2019 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package klo
+
+import (
+	"reflect"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/client-go/util/jsonpath"
+)
+
+type tinner struct {
+	A string
+	C int `json:"c"`
+}
+
+type tfields struct {
+	A      string
+	B      *tinner
+	Tagged string `json:"tagged,omitempty"`
+	Hidden string `json:"-"`
+	M      map[string]interface{}
+	I      interface{}
+	tembedded
+	// Being the last field without a JSON name, JSONPath looks up fields
+	// in here first, before looking up fields by their Go names.
+	Inline tinner
+}
+
+type tembedded struct {
+	E string
+}
+
+var _ = Describe("field paths", func() {
+
+	It("compiles only simple field paths", func() {
+		jp := jsonpath.New("test")
+		for _, expr := range []string{"{.A}", "{.a.b_c.D1}", "{._x}"} {
+			Expect(newFieldPath(jp, expr)).NotTo(BeNil(), expr)
+		}
+		Expect(newFieldPath(jp, "{.a.b}").names).To(Equal([]string{"a", "b"}))
+		for _, expr := range []string{
+			"", "{}", "{.}", "{.A}{.B}", "{.A[0]}", "{.A.*}", "{..A}", "{.1A}", "{.A-B}", "A", ".A", "{A}",
+		} {
+			Expect(newFieldPath(jp, expr)).To(BeNil(), expr)
+		}
+	})
+
+	It("finds the same values as JSONPath", func() {
+		values := []interface{}{
+			nil,
+			42,
+			tfields{},
+			tfields{
+				A:         "a",
+				B:         &tinner{A: "ba", C: 42},
+				Tagged:    "tagged",
+				Hidden:    "hidden",
+				M:         map[string]interface{}{"a": "ma", "n": map[string]int{"c": 1}},
+				I:         tinner{A: "ia"},
+				tembedded: tembedded{E: "e"},
+				Inline:    tinner{A: "inline", C: 666},
+			},
+			&tfields{A: "a", I: &tinner{C: 1}},
+			map[string]interface{}{"A": "a", "B": nil, "M": map[string]string{"a": "b"}},
+			map[string]int{"A": 1},
+			map[int]int{1: 1},
+			[]tfields{{A: "a"}},
+		}
+		for _, expr := range []string{
+			"{.A}", "{.B.A}", "{.B.c}", "{.B.C}", "{.Tagged}", "{.tagged}", "{.Hidden}",
+			"{.M.a}", "{.M.n.c}", "{.M.x}", "{.I.A}", "{.I.C}", "{.E}", "{.c}", "{.Inline.A}",
+			"{.nonexisting}", "{.A.B}",
+		} {
+			jp := jsonpath.New("test").AllowMissingKeys(true)
+			Expect(jp.Parse(expr)).To(Succeed())
+			fp := newFieldPath(jp, expr)
+			Expect(fp).NotTo(BeNil())
+			for _, v := range values {
+				expected, experr := jp.FindResults(v)
+				actual, err := findResults(jp, fp, nil, false, v)
+				if experr != nil {
+					Expect(err).To(HaveOccurred(), "%s on %#v", expr, v)
+					continue
+				}
+				Expect(err).NotTo(HaveOccurred(), "%s on %#v", expr, v)
+				Expect(actual).To(HaveLen(len(expected)), "%s on %#v", expr, v)
+				for idx := range expected {
+					Expect(actual[idx]).To(HaveLen(len(expected[idx])), "%s on %#v", expr, v)
+					for vidx := range expected[idx] {
+						Expect(actual[idx][vidx].Interface()).To(Equal(expected[idx][vidx].Interface()),
+							"%s on %#v", expr, v)
+					}
+				}
+			}
+		}
+	})
+
+	It("falls back to JSONPath when templates change", func() {
+		c := &Column{Name: "A"}
+		Expect(c.SetExpression("A")).To(Succeed())
+		Expect(c.fields).NotTo(BeNil())
+		Expect(c.Cell(tinner{A: "a"}).Text).To(Equal("a"))
+		c.Template = jsonpath.New("B")
+		Expect(c.Template.Parse("{.c}")).To(Succeed())
+		Expect(c.Cell(tinner{A: "a", C: 42}).Text).To(Equal("42"))
+
+		Expect(c.SetExpression("{.A[0]}")).To(Succeed())
+		Expect(c.fields).To(BeNil())
+	})
+
+	It("caches struct field indices", func() {
+		Expect(structFieldIndex(reflect.TypeOf(tfields{}), "c")).To(Equal([]int{7, 1}))
+		Expect(structFieldIndex(reflect.TypeOf(tfields{}), "E")).To(Equal([]int{6, 0}))
+		Expect(structFieldIndex(reflect.TypeOf(tfields{}), "nonexisting")).To(BeNil())
+
+		jp := jsonpath.New("test")
+		fp := newFieldPath(jp, "{.B.C}")
+		_, ok := fp.find(tfields{B: &tinner{C: 42}})
+		Expect(ok).To(BeTrue())
+		index, ok := fp.indices.Load(fieldStep{step: 1, typ: reflect.TypeOf(tinner{})})
+		Expect(ok).To(BeTrue())
+		Expect(index).To(Equal([]int{1}))
+	})
+
+})
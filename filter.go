@@ -0,0 +1,310 @@
+// Copyright 2019 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package klo
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// FilteringPrinter drops the items of collection values not matching all of
+// its predicates, before it writes the remaining items to the next printer
+// in the chain. Kubernetes-style List objects keep their wrapper, with just
+// their items filtered, and slices keep their type. Streams of items get
+// filtered item by item as they arrive. Other values either get passed on
+// unmodified if they match, or otherwise as an empty slice.
+type FilteringPrinter struct {
+	ChainedPrinter ValuePrinter // Next ValuePrinter we chain to.
+	predicates     []*predicate // Predicates all items must match.
+}
+
+// NewFilteringPrinter returns a printer that filters values according to
+// the specified predicates before passing them on to the next printer. Items
+// need to match all predicates in order to get passed on. Each predicate
+// consists of a JSONPath expression, optionally followed by an operator and
+// a value:
+//   - {.x} ... the expression has a (non-nil) value.
+//   - !{.x} ... the expression has no (non-nil) value.
+//   - {.x}=value or {.x}==value ... a value equals "value".
+//   - {.x}!=value ... no value equals "value".
+//   - {.x}=~regexp ... a value matches the regular expression.
+//   - {.x}!~regexp ... no value matches the regular expression.
+//   - {.x}<number, {.x}<=number, {.x}>number, {.x}>=number ... a numeric
+//     value compares accordingly.
+//
+// The JSONPath expressions accept the same relaxed syntax as custom columns,
+// including the "{$key}" pseudo expression referencing the keys of map
+// entries. Values get compared using their textual representations.
+func NewFilteringPrinter(predicates []string, p ValuePrinter) (ValuePrinter, error) {
+	if p == nil {
+		return nil, errors.New("nil ValuePrint to chain (hint: we cannot)")
+	}
+	fp := &FilteringPrinter{
+		ChainedPrinter: p,
+		predicates:     make([]*predicate, len(predicates)),
+	}
+	for idx, expr := range predicates {
+		pred, err := newPredicate(expr)
+		if err != nil {
+			return nil, err
+		}
+		fp.predicates[idx] = pred
+	}
+	return fp, nil
+}
+
+// Fprint first filters values according to the predicates, then chains to
+// the next ValuePrinter for printing.
+func (fp *FilteringPrinter) Fprint(w io.Writer, v interface{}) error {
+	if seq, elemType, ok := stream(v); ok {
+		var err error
+		filtered := seqOf(elemType, func(yield func(reflect.Value) bool) {
+			for it := range seq {
+				var match bool
+				if match, err = fp.matches(it.Interface()); err != nil {
+					return
+				}
+				if match && !yield(it) {
+					return
+				}
+			}
+		})
+		perr := fp.ChainedPrinter.Fprint(w, filtered)
+		if err != nil {
+			return err
+		}
+		return perr
+	}
+	items, ok := collection(v)
+	if !ok {
+		if !items.IsValid() {
+			return fp.ChainedPrinter.Fprint(w, v)
+		}
+		match, err := fp.matches(items.Interface())
+		if err != nil {
+			return err
+		}
+		if !match {
+			v = reflect.MakeSlice(reflect.SliceOf(items.Type()), 0, 0).Interface()
+		}
+		return fp.ChainedPrinter.Fprint(w, v)
+	}
+	sliceType := items.Type()
+	if sliceType.Kind() != reflect.Slice {
+		sliceType = reflect.SliceOf(sliceType.Elem())
+	}
+	filtered := reflect.MakeSlice(sliceType, 0, 0)
+	for idx := 0; idx < items.Len(); idx++ {
+		match, err := fp.matches(item(items, idx))
+		if err != nil {
+			return err
+		}
+		if match {
+			filtered = reflect.Append(filtered, items.Index(idx))
+		}
+	}
+	return fp.ChainedPrinter.Fprint(w, reshaped(v, filtered))
+}
+
+// matches returns true if the object matches all predicates.
+func (fp *FilteringPrinter) matches(obj interface{}) (bool, error) {
+	for _, pred := range fp.predicates {
+		match, err := pred.matches(obj)
+		if err != nil || !match {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// predicateOp is the comparison operation of a predicate.
+type predicateOp int
+
+const (
+	opExists predicateOp = iota
+	opNotExists
+	opEqual
+	opNotEqual
+	opMatch
+	opNotMatch
+	opLess
+	opLessEqual
+	opGreater
+	opGreaterEqual
+)
+
+// predicateOps maps the predicate operators to their operations; longer
+// operators come first, so they take precedence over their prefixes.
+var predicateOps = []struct {
+	op   string
+	pred predicateOp
+}{
+	{"==", opEqual},
+	{"!=", opNotEqual},
+	{"=~", opMatch},
+	{"!~", opNotMatch},
+	{"<=", opLessEqual},
+	{">=", opGreaterEqual},
+	{"=", opEqual},
+	{"<", opLess},
+	{">", opGreater},
+}
+
+// predicate matches objects based on the value(s) of a JSONPath expression.
+type predicate struct {
+	expr   Column         // JSONPath expression to evaluate.
+	op     predicateOp    // comparison operation.
+	value  string         // value to compare with.
+	number float64        // numeric value to compare with.
+	re     *regexp.Regexp // regular expression to match.
+}
+
+// newPredicate returns a new predicate for the specified predicate
+// expression, see also NewFilteringPrinter.
+func newPredicate(s string) (*predicate, error) {
+	pred := &predicate{expr: Column{Name: "filter"}}
+	expr, rest := s, ""
+	if strings.HasPrefix(s, "{") {
+		if idx := strings.Index(s, "}"); idx >= 0 {
+			expr, rest = s[:idx+1], s[idx+1:]
+		}
+	} else if strings.HasPrefix(s, "!") {
+		expr, pred.op = s[1:], opNotExists
+	} else if idx := strings.IndexAny(s, "=!<>"); idx >= 0 {
+		expr, rest = s[:idx], s[idx:]
+	}
+	if pred.op == opNotExists && strings.ContainsAny(expr, "=!<>") {
+		return nil, fmt.Errorf("unexpected filter predicate %q, expected '!<json-path-expr>'", s)
+	}
+	if expr == "" {
+		return nil, fmt.Errorf("filter predicate %q is missing its JSONPath expression", s)
+	}
+	if err := pred.expr.SetExpression(expr); err != nil {
+		return nil, err
+	}
+	if rest == "" {
+		return pred, nil
+	}
+	for _, op := range predicateOps {
+		if strings.HasPrefix(rest, op.op) {
+			pred.op, pred.value = op.pred, rest[len(op.op):]
+			break
+		}
+	}
+	switch pred.op {
+	case opExists:
+		return nil, fmt.Errorf("unexpected filter predicate %q, expected '<json-path-expr><op><value>'", s)
+	case opMatch, opNotMatch:
+		re, err := regexp.Compile(pred.value)
+		if err != nil {
+			return nil, err
+		}
+		pred.re = re
+	case opLess, opLessEqual, opGreater, opGreaterEqual:
+		number, err := strconv.ParseFloat(pred.value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("filter predicate %q needs a number to compare with", s)
+		}
+		pred.number = number
+	}
+	return pred, nil
+}
+
+// matches returns true if the object matches this predicate.
+func (p *predicate) matches(obj interface{}) (bool, error) {
+	res, err := findResults(p.expr.Template, p.expr.fields, p.expr.ranged,
+		p.expr.isKey, obj)
+	if err != nil {
+		return false, err
+	}
+	var vals []reflect.Value
+	for arridx := range res {
+		for _, val := range res[arridx] {
+			if val = indirect(val); val.IsValid() && !isNil(val) {
+				vals = append(vals, val)
+			}
+		}
+	}
+	switch p.op {
+	case opExists:
+		return len(vals) > 0, nil
+	case opNotExists:
+		return len(vals) == 0, nil
+	case opNotEqual, opNotMatch:
+		return !p.any(vals), nil
+	}
+	return p.any(vals), nil
+}
+
+// any returns true if any of the values satisfies this predicate's
+// comparison, ignoring any negation.
+func (p *predicate) any(vals []reflect.Value) bool {
+	for _, val := range vals {
+		switch p.op {
+		case opEqual, opNotEqual:
+			if fmt.Sprintf("%v", val.Interface()) == p.value {
+				return true
+			}
+		case opMatch, opNotMatch:
+			if p.re.MatchString(fmt.Sprintf("%v", val.Interface())) {
+				return true
+			}
+		default:
+			number, ok := numberOf(val)
+			if !ok {
+				continue
+			}
+			switch {
+			case p.op == opLess && number < p.number,
+				p.op == opLessEqual && number <= p.number,
+				p.op == opGreater && number > p.number,
+				p.op == opGreaterEqual && number >= p.number:
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isNil returns true if the value is a nil chan, func, map, or slice.
+func isNil(val reflect.Value) bool {
+	switch val.Kind() {
+	case reflect.Chan, reflect.Func, reflect.Map, reflect.Slice:
+		return val.IsNil()
+	}
+	return false
+}
+
+// numberOf returns the numeric value of an int, uint, or float value, or of
+// a string value representing a number.
+func numberOf(val reflect.Value) (float64, bool) {
+	switch val.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(val.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(val.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return val.Float(), true
+	case reflect.String:
+		number, err := strconv.ParseFloat(val.String(), 64)
+		return number, err == nil
+	}
+	return 0, false
+}
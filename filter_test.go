@@ -0,0 +1,161 @@
+// Copyright 2019 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package klo
+
+import (
+	"bytes"
+	"slices"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("filtering printer", func() {
+
+	type tpod struct {
+		Name     string
+		Status   string
+		Restarts int
+		CPU      string
+		Node     *string
+	}
+
+	node := "node-1"
+	pods := func() []tpod {
+		return []tpod{
+			{Name: "foo", Status: "Running", Restarts: 0, CPU: "0.5", Node: &node},
+			{Name: "bar", Status: "Pending", Restarts: 3, CPU: "n/a"},
+			{Name: "baz", Status: "Running", Restarts: 12, CPU: "2"},
+		}
+	}
+
+	names := func() ValuePrinter {
+		return GoodPrinter(NewCustomColumnsPrinterFromSpec("NAME:{.Name}"))
+	}
+
+	filtered := func(predicates ...string) string {
+		var out bytes.Buffer
+		p := GoodPrinter(NewFilteringPrinter(predicates, names()))
+		ExpectWithOffset(1, p.Fprint(&out, pods())).To(Succeed())
+		return out.String()
+	}
+
+	It("rejects invalid predicates", func() {
+		BadPrinter(NewFilteringPrinter([]string{"{.Name}=foo"}, nil))
+		for _, pred := range []string{
+			"", "=foo", "!", "{}=foo", "{.Name", "{.Name}foo", "!{.Name}=foo",
+			"{.Name}=~(", "{.Restarts}<many", "{.Restarts}>=",
+		} {
+			_, err := NewFilteringPrinter([]string{pred}, names())
+			Expect(err).To(HaveOccurred(), "predicate %q", pred)
+		}
+	})
+
+	It("filters by (in)equality", func() {
+		Expect(filtered()).To(Equal("NAME\nfoo\nbar\nbaz\n"))
+		Expect(filtered("{.Status}=Running")).To(Equal("NAME\nfoo\nbaz\n"))
+		Expect(filtered("Status==Running")).To(Equal("NAME\nfoo\nbaz\n"))
+		Expect(filtered(".Status!=Running")).To(Equal("NAME\nbar\n"))
+		Expect(filtered("{.Restarts}=3")).To(Equal("NAME\nbar\n"))
+		Expect(filtered("{.Node}=node-1")).To(Equal("NAME\nfoo\n"))
+		Expect(filtered("{.Status}=")).To(Equal("NAME\n"))
+	})
+
+	It("filters by regular expressions", func() {
+		Expect(filtered("{.Name}=~^ba")).To(Equal("NAME\nbar\nbaz\n"))
+		Expect(filtered("{.Name}!~^ba")).To(Equal("NAME\nfoo\n"))
+	})
+
+	It("filters by numeric comparisons", func() {
+		Expect(filtered("{.Restarts}<3")).To(Equal("NAME\nfoo\n"))
+		Expect(filtered("{.Restarts}<=3")).To(Equal("NAME\nfoo\nbar\n"))
+		Expect(filtered("{.Restarts}>3")).To(Equal("NAME\nbaz\n"))
+		Expect(filtered("{.Restarts}>=3")).To(Equal("NAME\nbar\nbaz\n"))
+		Expect(filtered("{.CPU}>0.25")).To(Equal("NAME\nfoo\nbaz\n"))
+		Expect(filtered("{.Name}>0")).To(Equal("NAME\n"))
+	})
+
+	It("filters by existence", func() {
+		Expect(filtered("{.Node}")).To(Equal("NAME\nfoo\n"))
+		Expect(filtered("!{.Node}")).To(Equal("NAME\nbar\nbaz\n"))
+		Expect(filtered("!Node")).To(Equal("NAME\nbar\nbaz\n"))
+		Expect(filtered("{.Missing}")).To(Equal("NAME\n"))
+	})
+
+	It("combines predicates", func() {
+		Expect(filtered("{.Status}=Running", "{.Restarts}>0")).To(Equal("NAME\nbaz\n"))
+		Expect(filtered("{.Status}=Running", "{.Restarts}>100")).To(Equal("NAME\n"))
+	})
+
+	It("filters maps, streams and single values", func() {
+		p := GoodPrinter(NewFilteringPrinter([]string{"{$key}!=b"},
+			GoodPrinter(NewCustomColumnsPrinterFromSpec("KEY:{$key},NAME:{.Name}"))))
+		m := map[string]tpod{"a": pods()[0], "b": pods()[1], "c": pods()[2]}
+		PrinterPass(p, m, "KEY  NAME\na    foo\nc    baz\n")
+
+		p = GoodPrinter(NewFilteringPrinter([]string{"{.Status}=Running"}, names()))
+		PrinterPass(p, slices.Values(pods()), "NAME\nfoo\nbaz\n")
+		PrinterPass(p, &pods()[0], "NAME\nfoo\n")
+		PrinterPass(p, pods()[1], "NAME\n")
+		PrinterPass(p, nil, "NAME\n")
+
+		j := GoodPrinter(NewFilteringPrinter([]string{"{.Status}=Running"}, &JSONPrinter{}))
+		PrinterPass(j, pods()[1], "[]\n")
+	})
+
+	It("keeps the shapes of List objects and slices", func() {
+		type tmeta struct {
+			RemainingItemCount *int64 `json:"remainingItemCount,omitempty"`
+		}
+		type tpodlist struct {
+			Kind     string `json:"kind"`
+			Metadata tmeta  `json:"metadata"`
+			Items    []tpod `json:"items"`
+		}
+		type tpods []tpod
+		list := &tpodlist{Kind: "PodList", Items: pods()}
+
+		c := &capturingPrinter{}
+		p := GoodPrinter(NewFilteringPrinter([]string{"{.Status}=Running"}, c))
+		Expect(p.Fprint(nil, list)).To(Succeed())
+		Expect(c.v).To(Equal(tpodlist{Kind: "PodList", Items: []tpod{pods()[0], pods()[2]}}))
+		Expect(list.Items).To(HaveLen(3))
+		Expect(p.Fprint(nil, tpods(pods()))).To(Succeed())
+		Expect(c.v).To(Equal(tpods{pods()[0], pods()[2]}))
+		Expect(p.Fprint(nil, [3]tpod(pods()))).To(Succeed())
+		Expect(c.v).To(Equal([]tpod{pods()[0], pods()[2]}))
+
+		l := GoodPrinter(NewLimitingPrinter(1, &JSONPrinter{Compact: true}))
+		p = GoodPrinter(NewFilteringPrinter([]string{"{.Status}=Running"}, l))
+		PrinterPass(p, list, `{"kind":"PodList","metadata":{"remainingItemCount":1},`+
+			`"items":[{"Name":"foo","Status":"Running","Restarts":0,"CPU":"0.5","Node":"node-1"}]}`+"\n")
+	})
+
+	It("chains with sorting printers", func() {
+		s := GoodPrinter(NewSortingPrinter("{.Name}", names()))
+		p := GoodPrinter(NewFilteringPrinter([]string{"{.Status}=Running"}, s))
+		PrinterPass(p, pods(), "NAME\nbaz\nfoo\n")
+		PrinterPass(p, slices.Values(pods()), "NAME\nbaz\nfoo\n")
+	})
+
+	It("reports evaluation errors", func() {
+		fp := GoodPrinter(NewFilteringPrinter([]string{"{.Name}=foo"}, names())).(*FilteringPrinter)
+		fp.predicates[0].expr.Template.AllowMissingKeys(false)
+		PrinterFail(fp, []map[string]int{{"a": 1}})
+		PrinterFail(fp, map[string]int{"a": 1})
+		PrinterFail(fp, slices.Values([]map[string]int{{"a": 1}}))
+	})
+
+})
@@ -0,0 +1,119 @@
+// Copyright 2019 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package klo
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Formatter renders a single value of a column into its cell text.
+type Formatter func(v interface{}) string
+
+var (
+	formattersMu sync.RWMutex
+	formatters   = map[string]Formatter{
+		"age": FormatAge,
+	}
+)
+
+// RegisterFormatter registers a named formatter, so that it can be referenced
+// by name, such as in "klo" struct field tags. Registering a formatter with
+// the name of an already registered formatter replaces the existing one.
+func RegisterFormatter(name string, f Formatter) {
+	formattersMu.Lock()
+	defer formattersMu.Unlock()
+	formatters[name] = f
+}
+
+// LookupFormatter returns the formatter registered under the specified name,
+// or nil if there is no such formatter.
+func LookupFormatter(name string) Formatter {
+	formattersMu.RLock()
+	defer formattersMu.RUnlock()
+	return formatters[name]
+}
+
+// now returns the current time; it's a variable so that tests can travel in
+// time.
+var now = time.Now
+
+// FormatAge renders the age of a point in time (time.Time) or a duration
+// (time.Duration) in the same terse, human-readable format as kubectl does,
+// such as "42s", "5m30s", "3h", or "12d". Other values are rendered in their
+// default format.
+func FormatAge(v interface{}) string {
+	switch t := v.(type) {
+	case time.Time:
+		if t.IsZero() {
+			return "<unknown>"
+		}
+		return humanDuration(now().Sub(t))
+	case *time.Time:
+		if t == nil {
+			return "<unknown>"
+		}
+		return FormatAge(*t)
+	case time.Duration:
+		return humanDuration(t)
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+// humanDuration returns a succinct representation of the duration d, with
+// limited precision. It follows kubectl's rendering of resource ages.
+func humanDuration(d time.Duration) string {
+	// Allow deviation no more than 2 seconds (excluded) to tolerate machine
+	// time inconsistence; it can be considered as almost now.
+	if seconds := int(d.Seconds()); seconds < -1 {
+		return "<invalid>"
+	} else if seconds < 0 {
+		return "0s"
+	} else if seconds < 60*2 {
+		return fmt.Sprintf("%ds", seconds)
+	}
+	minutes := int(d / time.Minute)
+	if minutes < 10 {
+		if s := int(d/time.Second) % 60; s != 0 {
+			return fmt.Sprintf("%dm%ds", minutes, s)
+		}
+		return fmt.Sprintf("%dm", minutes)
+	} else if minutes < 60*3 {
+		return fmt.Sprintf("%dm", minutes)
+	}
+	hours := int(d / time.Hour)
+	if hours < 8 {
+		if m := minutes % 60; m != 0 {
+			return fmt.Sprintf("%dh%dm", hours, m)
+		}
+		return fmt.Sprintf("%dh", hours)
+	} else if hours < 48 {
+		return fmt.Sprintf("%dh", hours)
+	} else if hours < 24*8 {
+		if h := hours % 24; h != 0 {
+			return fmt.Sprintf("%dd%dh", hours/24, h)
+		}
+		return fmt.Sprintf("%dd", hours/24)
+	} else if hours < 24*365*2 {
+		return fmt.Sprintf("%dd", hours/24)
+	} else if hours < 24*365*8 {
+		if dy := (hours / 24) % 365; dy != 0 {
+			return fmt.Sprintf("%dy%dd", hours/24/365, dy)
+		}
+		return fmt.Sprintf("%dy", hours/24/365)
+	}
+	return fmt.Sprintf("%dy", hours/24/365)
+}
@@ -0,0 +1,75 @@
+// Copyright 2019 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package klo
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("formatters", func() {
+
+	It("registers and looks up formatters", func() {
+		Expect(LookupFormatter("age")).NotTo(BeNil())
+		Expect(LookupFormatter("foobar")).To(BeNil())
+		RegisterFormatter("foobar", func(v interface{}) string { return "foobar" })
+		DeferCleanup(func() {
+			formattersMu.Lock()
+			defer formattersMu.Unlock()
+			delete(formatters, "foobar")
+		})
+		Expect(LookupFormatter("foobar")(42)).To(Equal("foobar"))
+	})
+
+	It("formats ages", func() {
+		oldnow := now
+		t0 := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+		now = func() time.Time { return t0 }
+		DeferCleanup(func() { now = oldnow })
+
+		created := t0.Add(-42 * time.Second)
+		Expect(FormatAge(created)).To(Equal("42s"))
+		Expect(FormatAge(&created)).To(Equal("42s"))
+		Expect(FormatAge((*time.Time)(nil))).To(Equal("<unknown>"))
+		Expect(FormatAge(time.Time{})).To(Equal("<unknown>"))
+		Expect(FormatAge(42)).To(Equal("42"))
+
+		for _, tt := range []struct {
+			d        time.Duration
+			expected string
+		}{
+			{-2 * time.Second, "<invalid>"},
+			{-time.Second / 2, "0s"},
+			{119 * time.Second, "119s"},
+			{5 * time.Minute, "5m"},
+			{5*time.Minute + 30*time.Second, "5m30s"},
+			{42 * time.Minute, "42m"},
+			{3 * time.Hour, "3h"},
+			{3*time.Hour + 5*time.Minute, "3h5m"},
+			{20 * time.Hour, "20h"},
+			{72 * time.Hour, "3d"},
+			{74 * time.Hour, "3d2h"},
+			{24 * 100 * time.Hour, "100d"},
+			{24 * 365 * 3 * time.Hour, "3y"},
+			{24 * (365*3 + 10) * time.Hour, "3y10d"},
+			{24 * 365 * 10 * time.Hour, "10y"},
+		} {
+			Expect(FormatAge(tt.d)).To(Equal(tt.expected), "duration %s", tt.d)
+		}
+	})
+
+})
@@ -1,6 +1,6 @@
 module github.com/thediveo/klo
 
-go 1.22.0
+go 1.23.0
 
 require (
 	github.com/fvbommel/sortorder v1.1.0
@@ -44,8 +44,10 @@ func NewGoTemplatePrinterWithFuncs(tmpl string, funcMap template.FuncMap) (Value
 	}, nil
 }
 
-// Fprint prints a value in JSON format.
+// Fprint prints a value in JSON format. Streams of items get collected into a
+// slice first.
 func (p *GoTemplatePrinter) Fprint(w io.Writer, v interface{}) (err error) {
+	v = collect(v)
 	defer func() {
 		if tp := recover(); tp != nil {
 			err = fmt.Errorf("template panicked: %+v", tp)
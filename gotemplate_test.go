@@ -15,6 +15,7 @@
 package klo
 
 import (
+	"slices"
 	"strconv"
 	"text/template"
 
@@ -37,6 +38,9 @@ var _ = Describe("Go template printer", func() {
 		p := GoodPrinter(NewGoTemplatePrinter(`{{range .}}{{.}}{{println}}{{end}}`))
 		PrinterPass(p, []string{"foo", "bar"}, `foo
 bar
+`)
+		PrinterPass(p, slices.Values([]string{"foo", "bar"}), `foo
+bar
 `)
 	})
 
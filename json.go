@@ -15,25 +15,220 @@
 package klo
 
 import (
+	"bytes"
 	"encoding/json"
+	"fmt"
 	"io"
+	"iter"
+	"reflect"
+	"strconv"
+	"strings"
 )
 
-// JSONPrinter prints values in JSON format.
-type JSONPrinter struct{}
+// JSONPrinter prints values in JSON format. The zero value prints values
+// indented by four spaces per level, with HTML-escaped strings, and a final
+// newline.
+type JSONPrinter struct {
+	// Print values compactly on a single line instead of indenting them.
+	Compact bool
+	// Indentation per nesting level; defaults to four spaces.
+	Indent string
+	// Print "<", ">", and "&" inside strings as they are, instead of escaping
+	// them as "\u003c", "\u003e", and "\u0026".
+	NoHTMLEscape bool
+	// Order the keys of all JSON objects alphabetically, including the keys
+	// of structs, instead of only the keys of maps.
+	SortKeys bool
+	// Don't terminate the output with a newline.
+	NoTrailingNewline bool
+	// Print JSON Lines (also known as NDJSON) instead: each item of a
+	// collection or stream gets printed compactly on its own line, without
+	// any enclosing array. Map entries get printed as MapEntry objects.
+	Lines bool
+	// Optionally wrap slices, arrays, and streams of items in a
+	// Kubernetes-style List object, except when printing JSON Lines.
+	List *ListEnvelope
+}
 
 // NewJSONPrinter returns a printer for outputting values in JSON format.
 func NewJSONPrinter() (ValuePrinter, error) {
 	return &JSONPrinter{}, nil
 }
 
-// Fprint prints a value in JSON format.
+// NewJSONLinesPrinter returns a printer for outputting values in JSON Lines
+// format, with one item per line.
+func NewJSONLinesPrinter() (ValuePrinter, error) {
+	return &JSONPrinter{Lines: true}, nil
+}
+
+// NewJSONPrinterWithOptions returns a printer for outputting values in JSON
+// format, configured by a comma-separated list of options:
+//   - "compact" prints values compactly on a single line.
+//   - "indent=N" indents by N spaces per level, "indent=tab" by tabs.
+//   - "noescape" doesn't HTML-escape "<", ">", and "&" inside strings.
+//   - "sortkeys" orders the keys of all objects alphabetically.
+//   - "nonewline" doesn't terminate the output with a newline.
+//   - "lines" prints JSON Lines, with one item per line.
+func NewJSONPrinterWithOptions(options string) (ValuePrinter, error) {
+	p := &JSONPrinter{}
+	if options == "" {
+		return p, nil
+	}
+	for _, option := range strings.Split(options, ",") {
+		name, arg, hasArg := strings.Cut(strings.TrimSpace(option), "=")
+		switch {
+		case name == "compact" && !hasArg:
+			p.Compact = true
+		case name == "indent" && arg == "tab":
+			p.Indent = "\t"
+		case name == "indent":
+			n, err := strconv.Atoi(arg)
+			if err != nil || n < 1 || n > 8 {
+				return nil, fmt.Errorf("invalid JSON indentation %q, expected 1 to 8 spaces or 'tab'", arg)
+			}
+			p.Indent = strings.Repeat(" ", n)
+		case name == "noescape" && !hasArg:
+			p.NoHTMLEscape = true
+		case name == "sortkeys" && !hasArg:
+			p.SortKeys = true
+		case name == "nonewline" && !hasArg:
+			p.NoTrailingNewline = true
+		case name == "lines" && !hasArg:
+			p.Lines = true
+		default:
+			return nil, fmt.Errorf("unexpected JSON option %q, expected 'compact', "+
+				"'indent=', 'noescape', 'sortkeys', 'nonewline', or 'lines'", option)
+		}
+	}
+	return p, nil
+}
+
+// Fprint prints a value in JSON format. Streams of items, that is,
+// channels, iter.Seq, and iter.Seq2, get printed item by item as a JSON
+// array, or as JSON Lines when Lines is set.
 func (p *JSONPrinter) Fprint(w io.Writer, v interface{}) error {
-	txt, err := json.MarshalIndent(v, "", "    ")
+	if p.Lines {
+		return p.fprintLines(w, v)
+	}
+	if seq, _, ok := stream(v); ok {
+		return p.fprintStream(w, seq)
+	}
+	txt, err := p.marshal(p.List.wrapSlice(v), "")
 	if err != nil {
 		return err
 	}
-	txt = append(txt, '\n')
-	_, err = w.Write(txt)
+	_, err = w.Write(append(txt, p.newline()...))
 	return err
 }
+
+// fprintStream prints the items of a stream as they arrive, producing the
+// same output as if the items were marshalled in a single slice.
+func (p *JSONPrinter) fprintStream(w io.Writer, seq iter.Seq[reflect.Value]) error {
+	head, tail, prefix := "", "", ""
+	if p.List != nil {
+		// Marshal the List object with an empty item list, so that the
+		// items then can go in between the List object's head and tail.
+		// The item list is the last array in the List object, as only its
+		// metadata follows. Marshalling the List object itself never fails.
+		txt, _ := p.marshal(p.List.wrap([]interface{}{}), "")
+		at := bytes.LastIndex(txt, []byte("[]"))
+		head, tail = string(txt[:at]), string(txt[at+2:])
+		if !p.Compact {
+			prefix = p.indent()
+		}
+	}
+	itemPrefix, open, sep, end := "", head+"[", ",", "]"
+	if !p.Compact {
+		itemPrefix = prefix + p.indent()
+		open, sep, end = head+"[\n"+itemPrefix, ",\n"+itemPrefix, "\n"+prefix+"]"
+	}
+	next := open
+	for it := range seq {
+		txt, err := p.marshal(it.Interface(), itemPrefix)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(w, next); err != nil {
+			return err
+		}
+		if _, err := w.Write(txt); err != nil {
+			return err
+		}
+		next = sep
+	}
+	if next == open {
+		next, end = head, "[]"
+	} else {
+		next = ""
+	}
+	_, err := io.WriteString(w, next+end+tail+p.newline())
+	return err
+}
+
+// fprintLines prints each item of a collection or stream on its own line,
+// or otherwise the value v itself on a single line.
+func (p *JSONPrinter) fprintLines(w io.Writer, v interface{}) error {
+	return rowObjects(v, func(obj interface{}) error {
+		txt, err := p.marshal(obj, "")
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(append(txt, '\n'))
+		return err
+	})
+}
+
+// marshal returns the JSON encoding of the value v, without any trailing
+// newline. Unless printing compactly or JSON Lines, all lines except the
+// first get prefixed by the specified prefix.
+func (p *JSONPrinter) marshal(v interface{}, prefix string) ([]byte, error) {
+	if p.SortKeys {
+		var err error
+		if v, err = sortedKeys(v); err != nil {
+			return nil, err
+		}
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(!p.NoHTMLEscape)
+	if !p.Compact && !p.Lines {
+		enc.SetIndent(prefix, p.indent())
+	}
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// indent returns the indentation per nesting level.
+func (p *JSONPrinter) indent() string {
+	if p.Indent == "" {
+		return "    "
+	}
+	return p.Indent
+}
+
+// newline returns the newline terminating the output, if any.
+func (p *JSONPrinter) newline() string {
+	if p.NoTrailingNewline {
+		return ""
+	}
+	return "\n"
+}
+
+// sortedKeys returns the generic JSON representation of the value v, using
+// maps for all JSON objects, so that marshalling it orders the keys of all
+// objects. Numbers are kept exactly as they are.
+func sortedKeys(v interface{}) (interface{}, error) {
+	txt, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(txt))
+	dec.UseNumber()
+	var generic interface{}
+	if err := dec.Decode(&generic); err != nil {
+		return nil, err
+	}
+	return generic, nil
+}
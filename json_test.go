@@ -15,6 +15,9 @@
 package klo
 
 import (
+	"encoding/json"
+	"slices"
+
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 )
@@ -34,9 +37,186 @@ var _ = Describe("JSON printer", func() {
 		// correctly handle making JSON marshalling fail and that we correctly
 		// handle it in the printer ... luckily,
 		// https://stackoverflow.com/a/33964549 has the answer as to how make it
-		// fail.
+		// fail. As channels are streams of items, we use a func instead.
+		p := GoodPrinter(NewJSONPrinter())
+		Expect(p.Fprint(nil, func() {})).ShouldNot(Succeed())
+		Expect(p.Fprint(nil, slices.Values([]interface{}{func() {}}))).ShouldNot(Succeed())
+		Expect(p.Fprint(&failingWriter{}, slices.Values([]int{42}))).ShouldNot(Succeed())
+		Expect(p.Fprint(&failingWriter{n: 1}, slices.Values([]int{42}))).ShouldNot(Succeed())
+		Expect(p.Fprint(&failingWriter{n: 2}, slices.Values([]int{42}))).ShouldNot(Succeed())
+	})
+
+	It("prints streams of items", func() {
+		type foo struct {
+			Foo string
+			Bar []int
+		}
+		items := []foo{{Foo: "foo", Bar: []int{1, 2}}, {Foo: "bar"}}
+		expected, _ := json.MarshalIndent(items, "", "    ")
 		p := GoodPrinter(NewJSONPrinter())
-		Expect(p.Fprint(nil, make(chan struct{}))).ShouldNot(Succeed())
+		PrinterPass(p, slices.Values(items), string(expected)+"\n")
+		ch := make(chan foo, len(items))
+		for _, item := range items {
+			ch <- item
+		}
+		close(ch)
+		PrinterPass(p, ch, string(expected)+"\n")
+		PrinterPass(p, slices.Values([]foo{}), "[]\n")
+	})
+
+	It("prints JSON according to options", func() {
+		type foo struct {
+			Zoo string
+			Cmd string
+			Bar []int `json:"bar,omitempty"`
+		}
+		items := []foo{{Zoo: "a", Cmd: "a && b <c>", Bar: []int{1}}, {Zoo: "b"}}
+
+		p := GoodPrinter(NewJSONPrinterWithOptions("compact"))
+		PrinterPass(p, items, `[{"Zoo":"a","Cmd":"a \u0026\u0026 b \u003cc\u003e","bar":[1]},{"Zoo":"b","Cmd":""}]`+"\n")
+		PrinterPass(p, slices.Values(items), `[{"Zoo":"a","Cmd":"a \u0026\u0026 b \u003cc\u003e","bar":[1]},{"Zoo":"b","Cmd":""}]`+"\n")
+		PrinterPass(p, slices.Values([]foo{}), "[]\n")
+
+		p = GoodPrinter(NewJSONPrinterWithOptions("compact, noescape,sortkeys,nonewline"))
+		PrinterPass(p, items, `[{"Cmd":"a && b <c>","Zoo":"a","bar":[1]},{"Cmd":"","Zoo":"b"}]`)
+		PrinterPass(p, slices.Values(items), `[{"Cmd":"a && b <c>","Zoo":"a","bar":[1]},{"Cmd":"","Zoo":"b"}]`)
+		PrinterPass(p, map[string]float64{"b": 1e21, "a": 0.1}, `{"a":0.1,"b":1e+21}`)
+
+		p = GoodPrinter(NewJSONPrinterWithOptions("indent=2"))
+		expected := `[
+  {
+    "Zoo": "a",
+    "Cmd": "a \u0026\u0026 b \u003cc\u003e",
+    "bar": [
+      1
+    ]
+  },
+  {
+    "Zoo": "b",
+    "Cmd": ""
+  }
+]
+`
+		PrinterPass(p, items, expected)
+		PrinterPass(p, slices.Values(items), expected)
+
+		p = GoodPrinter(NewJSONPrinterWithOptions("indent=tab"))
+		PrinterPass(p, items[1:], "[\n\t{\n\t\t\"Zoo\": \"b\",\n\t\t\"Cmd\": \"\"\n\t}\n]\n")
+
+		PrinterPass(GoodPrinter(NewJSONPrinterWithOptions("")), items[1:], `[
+    {
+        "Zoo": "b",
+        "Cmd": ""
+    }
+]
+`)
+
+		BadPrinter(NewJSONPrinterWithOptions("indent=0"))
+		BadPrinter(NewJSONPrinterWithOptions("indent=foo"))
+		BadPrinter(NewJSONPrinterWithOptions("compact=yes"))
+		BadPrinter(NewJSONPrinterWithOptions("compact,"))
+
+		p = GoodPrinter(NewJSONPrinterWithOptions("sortkeys"))
+		Expect(p.Fprint(nil, func() {})).ShouldNot(Succeed())
+	})
+
+	It("wraps slices in List objects", func() {
+		type foo struct {
+			Foo string
+			Bar []int `json:",omitempty"`
+		}
+		items := []foo{{Foo: "foo", Bar: []int{1, 2}}, {Foo: "bar"}}
+		expected := `{
+    "apiVersion": "v1",
+    "kind": "List",
+    "items": [
+        {
+            "Foo": "foo",
+            "Bar": [
+                1,
+                2
+            ]
+        },
+        {
+            "Foo": "bar"
+        }
+    ],
+    "metadata": {}
+}
+`
+		p := &JSONPrinter{List: &ListEnvelope{}}
+		PrinterPass(p, items, expected)
+		PrinterPass(p, [2]foo(items), expected)
+		PrinterPass(p, slices.Values(items), expected)
+		PrinterPass(p, items[1], "{\n    \"Foo\": \"bar\"\n}\n")
+		PrinterPass(p, []byte("foo"), "\"Zm9v\"\n")
+		PrinterPass(p, json.RawMessage(`{"Foo":"bar"}`), "{\n    \"Foo\": \"bar\"\n}\n")
+		PrinterPass(p, &tlist{Items: []tlistitem{{A: "x"}}}, `{
+    "Kind": "",
+    "Items": [
+        {
+            "A": "x"
+        }
+    ]
+}
+`)
+
+		expected = `{
+    "apiVersion": "v1",
+    "kind": "List",
+    "items": [],
+    "metadata": {}
+}
+`
+		PrinterPass(p, []foo(nil), expected)
+		PrinterPass(p, slices.Values([]foo{}), expected)
+
+		p = &JSONPrinter{Compact: true, SortKeys: true, List: &ListEnvelope{APIVersion: "example.org/v1", Kind: "FooList"}}
+		expected = `{"apiVersion":"example.org/v1","items":[{"Bar":[1,2],"Foo":"foo"},{"Foo":"bar"}],"kind":"FooList","metadata":{}}` + "\n"
+		PrinterPass(p, items, expected)
+		PrinterPass(p, slices.Values(items), expected)
+		PrinterPass(p, slices.Values([]foo{}), `{"apiVersion":"example.org/v1","items":[],"kind":"FooList","metadata":{}}`+"\n")
+
+		// JSON Lines never get wrapped.
+		p = &JSONPrinter{Lines: true, List: &ListEnvelope{}}
+		PrinterPass(p, items[1:], "{\"Foo\":\"bar\"}\n")
+	})
+
+	It("prints JSON Lines", func() {
+		type foo struct {
+			Foo string
+			Bar []int `json:",omitempty"`
+		}
+		items := []foo{{Foo: "a<b", Bar: []int{1, 2}}, {Foo: "b"}}
+		expected := "{\"Foo\":\"a\\u003cb\",\"Bar\":[1,2]}\n{\"Foo\":\"b\"}\n"
+
+		p := GoodPrinter(NewJSONLinesPrinter())
+		PrinterPass(p, items, expected)
+		PrinterPass(p, [2]foo(items), expected)
+		PrinterPass(p, &tlist{Items: []tlistitem{{A: "x"}, {A: "y"}}}, "{\"A\":\"x\"}\n{\"A\":\"y\"}\n")
+		PrinterPass(p, slices.Values(items), expected)
+		PrinterPass(p, items[1], "{\"Foo\":\"b\"}\n")
+		PrinterPass(p, map[string]int{"y": 2, "x": 1}, "{\"Key\":\"x\",\"Value\":1}\n{\"Key\":\"y\",\"Value\":2}\n")
+		PrinterPass(p, []foo{}, "")
+		PrinterPass(p, nil, "")
+		PrinterPass(p, json.RawMessage(`{"a":1}`), "{\"a\":1}\n")
+		PrinterPass(p, []json.RawMessage{[]byte(`{"a":1}`), []byte(`2`)}, "{\"a\":1}\n2\n")
+		PrinterPass(p, []byte("foo"), "\"Zm9v\"\n")
+
+		PrinterPass(GoodPrinter(NewJSONPrinterWithOptions("lines,noescape,indent=2")), items,
+			"{\"Foo\":\"a<b\",\"Bar\":[1,2]}\n{\"Foo\":\"b\"}\n")
+
+		Expect(p.Fprint(nil, []interface{}{func() {}})).ShouldNot(Succeed())
+		Expect(p.Fprint(&failingWriter{}, items)).ShouldNot(Succeed())
+	})
+
+	It("prints sorted and filtered JSON Lines", func() {
+		items := []tlistitem{{A: "c"}, {A: "a"}, {A: "b"}}
+		p := GoodPrinter(NewSortingPrinter("{.A}",
+			GoodPrinter(NewFilteringPrinter([]string{"{.A}!=b"},
+				GoodPrinter(NewJSONLinesPrinter())))))
+		PrinterPass(p, items, "{\"A\":\"a\"}\n{\"A\":\"c\"}\n")
+		PrinterPass(p, slices.Values(items), "{\"A\":\"a\"}\n{\"A\":\"c\"}\n")
 	})
 
 })
@@ -15,16 +15,19 @@
 package klo
 
 import (
+	"bytes"
 	"fmt"
 	"io"
+	"reflect"
 
 	"k8s.io/client-go/util/jsonpath"
 )
 
 // JSONPathPrinter prints values in JSON format.
 type JSONPathPrinter struct {
-	Expr *jsonpath.JSONPath // Compiled JSONPath expression.
-	raw  string             // Original JSONPath expression, to ease debugging.
+	Expr     *jsonpath.JSONPath // Compiled JSONPath expression.
+	raw      string             // Original JSONPath expression, to ease debugging.
+	compiled *jsonpath.JSONPath // Expression as originally compiled from raw.
 }
 
 // NewJSONPathPrinter returns a printer for outputting the values that were
@@ -40,18 +43,100 @@ func NewJSONPathPrinter(expr string) (ValuePrinter, error) {
 		return nil, err
 	}
 	return &JSONPathPrinter{
-		Expr: jp,
-		raw:  expr,
+		Expr:     jp,
+		raw:      expr,
+		compiled: jp,
 	}, nil
 }
 
 // Fprint prints fields of a value in text format, where the values are selected
-// using JSONPath expressions.
+// using JSONPath expressions. Streams of items get collected into a slice
+// first.
 func (p *JSONPathPrinter) Fprint(w io.Writer, v interface{}) error {
-	if err := p.Expr.Execute(w, v); err != nil {
+	v = collect(v)
+	var out bytes.Buffer
+	if err := p.execute(&out, v); err != nil {
 		return fmt.Errorf(
 			"JSONPath failure on expression %q for value %+v",
 			p.raw, v)
 	}
-	return nil
+	_, err := out.WriteTo(w)
+	return err
+}
+
+// execute executes the JSONPath expression on the value v, writing the
+// results to w. Compiled JSONPath expressions with ranges modify themselves
+// when executed, so they cannot be executed again. Thus, the expression gets
+// compiled afresh for each execution, unless Expr has been replaced.
+func (p *JSONPathPrinter) execute(w io.Writer, v interface{}) error {
+	if p.Expr != p.compiled {
+		return p.Expr.Execute(w, v)
+	}
+	jp := jsonpath.New("expr")
+	if err := jp.Parse(p.raw); err != nil {
+		return err
+	}
+	return jp.Execute(w, v)
+}
+
+// rangedExpr is a JSONPath expression with range blocks. Compiled JSONPath
+// expressions with ranges modify themselves when evaluated, so they cannot
+// be evaluated again, let alone concurrently. Instead, such expressions get
+// compiled afresh for each evaluation. In contrast, compiled expressions
+// without ranges are only read while being evaluated, so they are safe for
+// concurrent use.
+type rangedExpr struct {
+	template *jsonpath.JSONPath // JSONPath expression compiled from expr.
+	name     string             // Name of the JSONPath expression.
+	expr     string             // Original JSONPath expression.
+}
+
+// newRangedExpr returns the ranged expression for the specified JSONPath
+// expression, or nil if the expression doesn't contain any range blocks. The
+// template must have been compiled from the same expression, allowing
+// missing keys; ranged expressions only get compiled afresh as long as they
+// are used together with this template.
+func newRangedExpr(template *jsonpath.JSONPath, name, expr string) *rangedExpr {
+	parser, err := jsonpath.Parse(name, expr)
+	if err != nil || !hasRange(parser.Root.Nodes) {
+		return nil
+	}
+	return &rangedExpr{
+		template: template,
+		name:     name,
+		expr:     expr,
+	}
+}
+
+// hasRange returns true if the specified nodes of a parsed JSONPath
+// expression contain a range block.
+func hasRange(nodes []jsonpath.Node) bool {
+	for _, node := range nodes {
+		switch node := node.(type) {
+		case *jsonpath.IdentifierNode:
+			if node.Name == "range" {
+				return true
+			}
+		case *jsonpath.ListNode:
+			if hasRange(node.Nodes) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// findTemplateResults evaluates a compiled JSONPath expression on the
+// specified data. If the expression has range blocks, then it gets compiled
+// afresh from its ranged expression for this evaluation.
+func findTemplateResults(
+	jp *jsonpath.JSONPath, ranged *rangedExpr, data interface{},
+) ([][]reflect.Value, error) {
+	if ranged != nil && ranged.template == jp {
+		jp = jsonpath.New(ranged.name).AllowMissingKeys(true)
+		if err := jp.Parse(ranged.expr); err != nil {
+			return nil, err
+		}
+	}
+	return jp.FindResults(data)
 }
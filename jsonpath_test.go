@@ -15,7 +15,11 @@
 package klo
 
 import (
+	"slices"
+
 	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/client-go/util/jsonpath"
 )
 
 var _ = Describe("JSONPath printer", func() {
@@ -30,6 +34,42 @@ var _ = Describe("JSONPath printer", func() {
 
 		p = GoodPrinter(NewJSONPathPrinter("{.Nothing}"))
 		PrinterFail(p, f)
+
+		p = GoodPrinter(NewJSONPathPrinter("{[*].Foo}"))
+		PrinterPass(p, slices.Values([]struct{ Foo string }{f, f}), `bar bar`)
+
+		p = GoodPrinter(NewJSONPathPrinter("{range [*]}{.Foo}{';'}{end}"))
+		PrinterPass(p, []struct{ Foo string }{f, f}, `bar;bar;`)
+		PrinterPass(p, []struct{ Foo string }{f}, `bar;`)
+
+		p.(*JSONPathPrinter).Expr = jsonpath.New("foo")
+		Expect(p.(*JSONPathPrinter).Expr.Parse("{.Foo}")).To(Succeed())
+		PrinterPass(p, f, `bar`)
+	})
+
+	It("evaluates expressions with ranges repeatedly", func() {
+		Expect(newRangedExpr(nil, "x", "{.Foo}")).To(BeNil())
+		Expect(newRangedExpr(nil, "x", "{.Foo")).To(BeNil())
+
+		expr := "{range .Foos[*]}{.Foo}{end}"
+		jp := jsonpath.New("x").AllowMissingKeys(true)
+		Expect(jp.Parse(expr)).To(Succeed())
+		ranged := newRangedExpr(jp, "x", expr)
+		Expect(ranged).NotTo(BeNil())
+
+		type foo struct{ Foo string }
+		type foos struct{ Foos []foo }
+		for _, v := range []foos{
+			{Foos: []foo{{"bar"}, {"baz"}}},
+			{Foos: []foo{{"qux"}}},
+		} {
+			res, err := findTemplateResults(jp, ranged, v)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(res).To(HaveLen(len(v.Foos)))
+			for idx := range res {
+				Expect(res[idx][0].Interface()).To(Equal(v.Foos[idx].Foo))
+			}
+		}
 	})
 
 })
@@ -0,0 +1,258 @@
+// Copyright 2019 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package klo
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+)
+
+// LimitingPrinter limits collection values to either their first or last
+// items, before it writes them to the next printer in the chain. Kubernetes-
+// style List objects keep their wrapper, with the number of dropped items
+// added to their RemainingItemCount metadata, if any. The same goes for the
+// List objects of JSON and YAML printers down the chain wrapping collections
+// of items, see also ListEnvelope. Streams of items get limited as they
+// arrive, except when limiting to their last items, which requires
+// collecting them first; thus, tail printers never return on unbounded
+// streams, such as channels that never get closed. Other values get passed
+// on unmodified.
+type LimitingPrinter struct {
+	ChainedPrinter ValuePrinter // Next ValuePrinter we chain to.
+	Limit          int          // Maximum number of items; 0 is unlimited.
+	Tail           bool         // Keep the last instead of the first items.
+	// Print a trailer line "... and N more" after tables with dropped items.
+	// Trailers are never printed for other output formats, such as JSON. As
+	// streams might be unbounded, their items don't get counted beyond the
+	// first dropped item, so their trailer line reads "... and more".
+	Trailer bool
+}
+
+// NewLimitingPrinter returns a printer that passes on only the first limit
+// items of collections and streams to the next printer. A limit of 0 passes
+// on all items.
+func NewLimitingPrinter(limit int, p ValuePrinter) (ValuePrinter, error) {
+	return newLimitingPrinter(limit, false, p)
+}
+
+// NewTailPrinter returns a printer that passes on only the last limit items
+// of collections and streams to the next printer. A limit of 0 passes on all
+// items.
+func NewTailPrinter(limit int, p ValuePrinter) (ValuePrinter, error) {
+	return newLimitingPrinter(limit, true, p)
+}
+
+func newLimitingPrinter(limit int, tail bool, p ValuePrinter) (ValuePrinter, error) {
+	if limit < 0 {
+		return nil, fmt.Errorf("invalid negative limit %d", limit)
+	}
+	if p == nil {
+		return nil, errors.New("nil ValuePrint to chain (hint: we cannot)")
+	}
+	return &LimitingPrinter{
+		ChainedPrinter: p,
+		Limit:          limit,
+		Tail:           tail,
+	}, nil
+}
+
+// Fprint first limits values to their first or last items, then chains to
+// the next ValuePrinter for printing. Streams of items get consumed only up
+// to the limit. Only when printing a trailer, one more item gets waited for
+// in order to know whether items got dropped.
+func (lp *LimitingPrinter) Fprint(w io.Writer, v interface{}) error {
+	if lp.Limit == 0 {
+		return lp.ChainedPrinter.Fprint(w, v)
+	}
+	if seq, elemType, ok := stream(v); ok && !lp.Tail {
+		trailer := lp.trailer()
+		remaining := 0
+		limited := seqOf(elemType, func(yield func(reflect.Value) bool) {
+			count := 0
+			for it := range seq {
+				if count == lp.Limit {
+					// There's at least one more item, but we don't know
+					// how many more.
+					remaining = -1
+					return
+				}
+				if !yield(it) {
+					return
+				}
+				// Without a trailer, don't wait for the next item, which
+				// might never arrive.
+				if count++; count == lp.Limit && !trailer {
+					return
+				}
+			}
+		})
+		if err := lp.ChainedPrinter.Fprint(w, limited); err != nil {
+			return err
+		}
+		return lp.printTrailer(w, remaining)
+	}
+	v = collect(v)
+	items, ok := collection(v)
+	if !ok || items.Len() <= lp.Limit {
+		return lp.ChainedPrinter.Fprint(w, v)
+	}
+	// Arrays can only be sliced when addressable, so we might need to slice
+	// a copy of the array instead.
+	if items.Kind() == reflect.Array && !items.CanAddr() {
+		sl := reflect.MakeSlice(reflect.SliceOf(items.Type().Elem()), items.Len(), items.Len())
+		reflect.Copy(sl, items)
+		items = sl
+	}
+	remaining := items.Len() - lp.Limit
+	if lp.Tail {
+		items = items.Slice(remaining, items.Len())
+	} else {
+		items = items.Slice(0, lp.Limit)
+	}
+	limited := lp.limited(v, items, remaining)
+	if err := lp.ChainedPrinter.Fprint(w, limited); err != nil {
+		return err
+	}
+	return lp.printTrailer(w, remaining)
+}
+
+// limited returns the limited items of the value v, keeping the List object
+// wrapper of v, if any. Otherwise, if the chained printers wrap items in a
+// List object, then the limited items get wrapped here already, so that the
+// List object's metadata can tell the number of remaining items.
+func (lp *LimitingPrinter) limited(
+	v interface{}, items reflect.Value, remaining int,
+) interface{} {
+	if _, ok := withItems(v, items); !ok {
+		if env := listEnvelope(lp.ChainedPrinter); env != nil {
+			list := env.wrap(items.Interface())
+			count := int64(remaining)
+			list.Metadata.RemainingItemCount = &count
+			return list
+		}
+	}
+	return limitedList(v, items, remaining)
+}
+
+// trailer returns true if a trailer needs to be printed, that is, if asked
+// to do so and the next printer(s) in the chain end in a table printer.
+func (lp *LimitingPrinter) trailer() bool {
+	return lp.Trailer && isTablePrinter(lp.ChainedPrinter)
+}
+
+// printTrailer prints a trailer line with the number of remaining items, if
+// necessary. A negative number of remaining items stands for an unknown
+// number of items.
+func (lp *LimitingPrinter) printTrailer(w io.Writer, remaining int) error {
+	if remaining == 0 || !lp.trailer() {
+		return nil
+	}
+	if remaining < 0 {
+		_, err := io.WriteString(w, "... and more\n")
+		return err
+	}
+	_, err := fmt.Fprintf(w, "... and %d more\n", remaining)
+	return err
+}
+
+// limitedList returns a copy of the Kubernetes-style List object v with its
+// Items replaced by the limited items, and the number of remaining items
+// added to its RemainingItemCount metadata, if present. If v isn't a List
+// object with an Items slice, then the limited items are returned instead.
+func limitedList(v interface{}, items reflect.Value, remaining int) interface{} {
+	list, ok := withItems(v, items)
+	if !ok {
+		return items.Interface()
+	}
+	if count, ok := remainingItemCount(list); ok {
+		switch count.Kind() {
+		case reflect.Ptr:
+			n := reflect.New(count.Type().Elem())
+			if !count.IsNil() {
+				n.Elem().Set(count.Elem())
+			}
+			n.Elem().SetInt(n.Elem().Int() + int64(remaining))
+			count.Set(n)
+		default:
+			count.SetInt(count.Int() + int64(remaining))
+		}
+	}
+	return list.Interface()
+}
+
+// remainingItemCount returns the settable RemainingItemCount field of a
+// List object, either an embedded one, or one inside a ListMeta or Metadata
+// struct field. The field must be either a signed integer or a pointer to a
+// signed integer.
+func remainingItemCount(list reflect.Value) (reflect.Value, bool) {
+	// Fields promoted from nil embedded struct pointers cannot be set, so we
+	// need to carefully skip them.
+	field := func(val reflect.Value, name string) (reflect.Value, bool) {
+		f, ok := val.Type().FieldByName(name)
+		if !ok || !f.IsExported() {
+			return reflect.Value{}, false
+		}
+		fval, err := val.FieldByIndexErr(f.Index)
+		return fval, err == nil
+	}
+	candidates := []reflect.Value{list}
+	for _, name := range []string{"ListMeta", "Metadata"} {
+		if meta, ok := field(list, name); ok && meta.Kind() == reflect.Struct {
+			candidates = append(candidates, meta)
+		}
+	}
+	for _, candidate := range candidates {
+		count, ok := field(candidate, "RemainingItemCount")
+		if !ok {
+			continue
+		}
+		kind := count.Kind()
+		if kind == reflect.Ptr {
+			kind = count.Type().Elem().Kind()
+		}
+		switch kind {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return count, true
+		}
+	}
+	return reflect.Value{}, false
+}
+
+// isTablePrinter returns true if the printer is a table printer, or a chain
+// of printers ending in a table printer.
+func isTablePrinter(p ValuePrinter) bool {
+	return tablePrinter(p) != nil
+}
+
+// tablePrinter returns the custom-columns printer at the end of a chain of
+// printers, or nil if the chain doesn't end in a custom-columns printer.
+func tablePrinter(p ValuePrinter) *CustomColumnsPrinter {
+	for {
+		switch cp := p.(type) {
+		case *CustomColumnsPrinter:
+			return cp
+		case *SortingPrinter:
+			p = cp.ChainedPrinter
+		case *FilteringPrinter:
+			p = cp.ChainedPrinter
+		case *LimitingPrinter:
+			p = cp.ChainedPrinter
+		default:
+			return nil
+		}
+	}
+}
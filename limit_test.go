@@ -0,0 +1,196 @@
+// Copyright 2019 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package klo
+
+import (
+	"bytes"
+	"encoding/json"
+	"slices"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("limiting printers", func() {
+
+	type tmeta struct {
+		RemainingItemCount *int64 `json:"remainingItemCount,omitempty"`
+	}
+
+	type tmetalist struct {
+		Kind     string      `json:"kind"`
+		Metadata tmeta       `json:"metadata"`
+		Items    []tlistitem `json:"items"`
+	}
+
+	items := func() []tlistitem {
+		return []tlistitem{{A: "a"}, {A: "b"}, {A: "c"}, {A: "d"}}
+	}
+
+	table := func() ValuePrinter {
+		return GoodPrinter(NewCustomColumnsPrinterFromSpec("A:{.A}"))
+	}
+
+	It("rejects invalid limits and missing printers", func() {
+		BadPrinter(NewLimitingPrinter(-1, table()))
+		BadPrinter(NewTailPrinter(1, nil))
+	})
+
+	It("limits collections to their first or last items", func() {
+		p := GoodPrinter(NewLimitingPrinter(2, table()))
+		PrinterPass(p, items(), "A\na\nb\n")
+		PrinterPass(p, [4]tlistitem(items()), "A\na\nb\n")
+		PrinterPass(p, &tlist{Items: items()}, "A\na\nb\n")
+		PrinterPass(p, items()[:1], "A\na\n")
+		PrinterPass(p, tlistitem{A: "x"}, "A\nx\n")
+
+		p = GoodPrinter(NewTailPrinter(3, table()))
+		PrinterPass(p, items(), "A\nb\nc\nd\n")
+		PrinterPass(p, slices.Values(items()), "A\nb\nc\nd\n")
+
+		p = GoodPrinter(NewLimitingPrinter(0, table()))
+		PrinterPass(p, items(), "A\na\nb\nc\nd\n")
+
+		m := GoodPrinter(NewTailPrinter(1, GoodPrinter(NewCustomColumnsPrinterFromSpec("KEY:{$key}"))))
+		PrinterPass(m, map[string]int{"x": 1, "y": 2}, "KEY\ny\n")
+	})
+
+	It("doesn't modify the input", func() {
+		sl := items()
+		list := tlist{Items: sl}
+		p := GoodPrinter(NewLimitingPrinter(1, table()))
+		PrinterPass(p, &list, "A\na\n")
+		Expect(list.Items).To(HaveLen(4))
+		Expect(sl).To(Equal(items()))
+	})
+
+	It("limits streams", func() {
+		p := GoodPrinter(NewLimitingPrinter(2, table()))
+		consumed := 0
+		seq := func(yield func(tlistitem) bool) {
+			for _, it := range items() {
+				consumed++
+				if !yield(it) {
+					return
+				}
+			}
+		}
+		PrinterPass(p, seq, "A\na\nb\n")
+		Expect(consumed).To(Equal(2))
+
+		// Without a trailer, don't wait for items beyond the limit to
+		// arrive, such as on channels that never get closed.
+		pending := make(chan tlistitem, 2)
+		pending <- items()[0]
+		pending <- items()[1]
+		PrinterPass(p, pending, "A\na\nb\n")
+
+		p.(*LimitingPrinter).Trailer = true
+		consumed = 0
+		PrinterPass(p, seq, "A\na\nb\n... and more\n")
+		Expect(consumed).To(Equal(3))
+		PrinterPass(p, slices.Values(items()[:2]), "A\na\nb\n")
+
+		// Unbounded streams, such as channels that never get closed.
+		ch := make(chan tlistitem, 4)
+		for _, it := range items() {
+			ch <- it
+		}
+		PrinterPass(p, ch, "A\na\nb\n... and more\n")
+		endless := func(yield func(tlistitem) bool) {
+			for yield(tlistitem{A: "x"}) {
+			}
+		}
+		PrinterPass(p, endless, "A\nx\nx\n... and more\n")
+	})
+
+	It("prints trailers only for tables", func() {
+		lp := GoodPrinter(NewLimitingPrinter(1, table())).(*LimitingPrinter)
+		lp.Trailer = true
+		PrinterPass(lp, items(), "A\na\n... and 3 more\n")
+		PrinterPass(lp, items()[:1], "A\na\n")
+
+		sp := GoodPrinter(NewSortingPrinter("{.A}", table()))
+		lp = GoodPrinter(NewTailPrinter(1, sp)).(*LimitingPrinter)
+		lp.Trailer = true
+		PrinterPass(lp, items(), "A\nd\n... and 3 more\n")
+
+		lp = GoodPrinter(NewLimitingPrinter(1, &JSONPrinter{})).(*LimitingPrinter)
+		lp.Trailer = true
+		PrinterPass(lp, []string{"foo", "bar"}, "[\n    \"foo\"\n]\n")
+
+		Expect(lp.Fprint(&failingWriter{}, []string{"foo", "bar"})).NotTo(Succeed())
+		lp.ChainedPrinter = table()
+		Expect(lp.Fprint(&failingWriter{n: 1}, []string{"foo", "bar"})).NotTo(Succeed())
+		Expect(lp.Fprint(&failingWriter{}, slices.Values([]string{"foo", "bar"}))).NotTo(Succeed())
+	})
+
+	It("sets the remaining item count of List objects", func() {
+		p := GoodPrinter(NewLimitingPrinter(1, &JSONPrinter{}))
+		var out bytes.Buffer
+		Expect(p.Fprint(&out, tmetalist{Kind: "List", Items: items()})).To(Succeed())
+		var list tmetalist
+		Expect(json.Unmarshal(out.Bytes(), &list)).To(Succeed())
+		Expect(list.Kind).To(Equal("List"))
+		Expect(list.Items).To(Equal(items()[:1]))
+		Expect(list.Metadata.RemainingItemCount).NotTo(BeNil())
+		Expect(*list.Metadata.RemainingItemCount).To(Equal(int64(3)))
+
+		remaining := int64(10)
+		out.Reset()
+		Expect(p.Fprint(&out, &tmetalist{Metadata: tmeta{RemainingItemCount: &remaining}, Items: items()})).To(Succeed())
+		Expect(json.Unmarshal(out.Bytes(), &list)).To(Succeed())
+		Expect(*list.Metadata.RemainingItemCount).To(Equal(int64(13)))
+		Expect(remaining).To(Equal(int64(10)))
+
+		type tembedded struct {
+			RemainingItemCount int
+			Items              []tlistitem
+		}
+		out.Reset()
+		Expect(p.Fprint(&out, tembedded{Items: items()})).To(Succeed())
+		var embedded tembedded
+		Expect(json.Unmarshal(out.Bytes(), &embedded)).To(Succeed())
+		Expect(embedded.RemainingItemCount).To(Equal(3))
+	})
+
+	It("sets the remaining item count of List envelopes", func() {
+		items := []tlistitem{{A: "c"}, {A: "a"}, {A: "b"}, {A: "d"}}
+		jp := &JSONPrinter{Compact: true, List: &ListEnvelope{}}
+		p := GoodPrinter(NewLimitingPrinter(2, jp))
+		PrinterPass(p, items,
+			`{"apiVersion":"v1","kind":"List","items":[{"A":"c"},{"A":"a"}],"metadata":{"remainingItemCount":2}}`+"\n")
+		PrinterPass(p, items[:2],
+			`{"apiVersion":"v1","kind":"List","items":[{"A":"c"},{"A":"a"}],"metadata":{}}`+"\n")
+
+		// Sorting and filtering down the chain keep the List envelope, as
+		// do further limiting printers.
+		p = GoodPrinter(NewTailPrinter(3, GoodPrinter(NewFilteringPrinter([]string{"{.A}!=b"},
+			GoodPrinter(NewSortingPrinter("{.A}", GoodPrinter(NewLimitingPrinter(1, jp))))))))
+		PrinterPass(p, items,
+			`{"apiVersion":"v1","kind":"List","items":[{"A":"a"}],"metadata":{"remainingItemCount":2}}`+"\n")
+
+		p = GoodPrinter(NewLimitingPrinter(1, &YAMLPrinter{List: &ListEnvelope{}}))
+		PrinterPass(p, items,
+			"apiVersion: v1\nitems:\n- A: c\nkind: List\nmetadata:\n  remainingItemCount: 3\n")
+
+		// Neither JSON Lines nor YAML documents get wrapped.
+		p = GoodPrinter(NewLimitingPrinter(1, &JSONPrinter{Lines: true, List: &ListEnvelope{}}))
+		PrinterPass(p, items, `{"A":"c"}`+"\n")
+		p = GoodPrinter(NewLimitingPrinter(1, &YAMLPrinter{Documents: true, List: &ListEnvelope{}}))
+		PrinterPass(p, items, "A: c\n")
+	})
+
+})
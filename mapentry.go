@@ -0,0 +1,90 @@
+// Copyright 2019 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package klo
+
+import (
+	"reflect"
+	"sort"
+
+	"k8s.io/client-go/util/jsonpath"
+)
+
+// MapEntry is a single key-value entry of a map. CustomColumnsPrinter prints
+// maps as well as slices of map entries as tables with one row per entry,
+// where the columns' JSONPath expressions get evaluated on the entry's value.
+// The entry's key is available through the "{$key}" pseudo expression.
+type MapEntry struct {
+	Key   interface{}
+	Value interface{}
+}
+
+// keyExpr is the pseudo JSONPath expression referencing the key of a map
+// entry, instead of its value.
+const keyExpr = "{$key}"
+
+// sortedMapEntries returns the entries of the specified map, sorted by their
+// keys. Entries with equal keys, such as NaN float keys, are sorted by their
+// values. The entries get collected while iterating over the map, as keys
+// like NaN cannot be used for indexing the map later.
+func sortedMapEntries(m reflect.Value) []MapEntry {
+	type entry struct{ key, value reflect.Value }
+	reflected := make([]entry, 0, m.Len())
+	for iter := m.MapRange(); iter.Next(); {
+		reflected = append(reflected, entry{key: iter.Key(), value: iter.Value()})
+	}
+	sort.Slice(reflected, func(i, j int) bool {
+		if c := compareReflected(reflected[i].key, reflected[j].key); c != 0 {
+			return c < 0
+		}
+		return reflectedLess(reflected[i].value, reflected[j].value)
+	})
+	entries := make([]MapEntry, len(reflected))
+	for idx, e := range reflected {
+		entries[idx] = MapEntry{
+			Key:   e.key.Interface(),
+			Value: e.value.Interface(),
+		}
+	}
+	return entries
+}
+
+// findResults evaluates a compiled JSONPath expression on a row object. If
+// the row object is a map entry, then the expression gets evaluated on the
+// entry's value. If isKey is true, then the expression is the "{$key}" pseudo
+// expression instead, which results in the map entry's key, or in no result
+// at all if the row object isn't a map entry. If the expression is a simple
+// field path, then it gets evaluated using the faster field path instead.
+// Expressions with range blocks get compiled afresh for each evaluation.
+func findResults(
+	jp *jsonpath.JSONPath, fields *fieldPath, ranged *rangedExpr,
+	isKey bool, rowval interface{},
+) ([][]reflect.Value, error) {
+	entry, ok := rowval.(MapEntry)
+	if isKey {
+		if !ok {
+			return nil, nil
+		}
+		return [][]reflect.Value{{reflect.ValueOf(entry.Key)}}, nil
+	}
+	if ok {
+		rowval = entry.Value
+	}
+	if fields != nil && fields.template == jp {
+		if val, ok := fields.find(rowval); ok {
+			return [][]reflect.Value{{val}}, nil
+		}
+	}
+	return findTemplateResults(jp, ranged, rowval)
+}
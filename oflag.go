@@ -25,6 +25,8 @@ import (
 
 // Specs specifies custom-column formats for the default columns in
 // "-o=customcolumns" mode, and for the "-o=wide" wide columns mode.
+// Alternatively, the default and wide columns can be derived from a struct
+// type, see also NewCustomColumnsPrinterFromStruct.
 type Specs struct {
 	// default custom-columns spec in format
 	// "<header>:<json-path-expr>[,<header>:json-path-expr>]..."
@@ -32,12 +34,25 @@ type Specs struct {
 	// wide custom-columns spec in format
 	// "<header>:<json-path-expr>[,<header>:json-path-expr>]..."
 	WideColumnSpec string
+	// optional value of a struct type to derive the default and wide columns
+	// from when DefaultColumnSpec and WideColumnSpec respectively are empty.
+	ColumnsFromStruct interface{}
 	// optional separate Go template argument to output formats "go-template"
 	// and "go-template-file". For "go-template" the arg contains the
 	// template, for "go-template-file" it contains the template filename.
 	GoTemplateArg string
 	// optional any functions to be made available in go template"
 	GoTemplateFuncMap template.FuncMap
+	// optional color mode for the custom-columns output formats; defaults to
+	// coloring only terminal output, unless NO_COLOR is set.
+	Color ColorMode
+	// optional color for the custom-columns headers, such as Bold.
+	HeaderColor Color
+	// optional color rules for custom-columns cells, keyed by column header.
+	ColumnColors map[string][]ColorRule
+	// optional Kubernetes-style List object wrapping slices of items in the
+	// "json" and "yaml" output formats.
+	List *ListEnvelope
 }
 
 // PrinterFromFlag returns a suitable value printer according to the output
@@ -52,22 +67,33 @@ func PrinterFromFlag(flagvalue string, specs *Specs) (ValuePrinter, error) {
 	if specs == nil {
 		specs = &Specs{}
 	}
-	// If no output format is specified, default to custom columns.
+	// If no output format is specified, default to custom columns, which
+	// might be derived from a struct type.
 	if flagvalue == "" {
+		if specs.DefaultColumnSpec == "" && specs.ColumnsFromStruct != nil {
+			return specs.colorize(NewCustomColumnsPrinterFromStruct(specs.ColumnsFromStruct, false))
+		}
 		flagvalue = "custom-columns=" + specs.DefaultColumnSpec
 	}
 	// Do we support "-o wide"? Then map this to "-o customcolumns=..." for
-	// the specified wide columns spec.
-	if flagvalue == "wide" && specs.WideColumnSpec != "" {
-		flagvalue = "custom-columns=" + specs.WideColumnSpec
+	// the specified wide columns spec, or derive the wide columns from a
+	// struct type.
+	if flagvalue == "wide" {
+		if specs.WideColumnSpec != "" {
+			flagvalue = "custom-columns=" + specs.WideColumnSpec
+		} else if specs.ColumnsFromStruct != nil {
+			return specs.colorize(NewCustomColumnsPrinterFromStruct(specs.ColumnsFromStruct, true))
+		}
 	}
-	ov := strings.Split(flagvalue, "=")
+	// Only split off the output format, as its argument might contain "="
+	// itself, such as in "json=indent=2".
+	ov := strings.SplitN(flagvalue, "=", 2)
 	switch ov[0] {
 	case "custom-columns":
 		if len(ov) != 2 {
 			return nil, fmt.Errorf("missing custom columns specification")
 		}
-		return NewCustomColumnsPrinterFromSpec(ov[1])
+		return specs.colorize(NewCustomColumnsPrinterFromSpec(ov[1]))
 	case "custom-columns-file":
 		if len(ov) != 2 {
 			return nil, fmt.Errorf("missing custom columns filename")
@@ -77,7 +103,7 @@ func PrinterFromFlag(flagvalue string, specs *Specs) (ValuePrinter, error) {
 			return nil, err
 		}
 		defer f.Close()
-		return NewCustomColumnsPrinterFromTemplate(f)
+		return specs.colorize(NewCustomColumnsPrinterFromTemplate(f))
 	case "go-template":
 		if specs.GoTemplateArg == "" && len(ov) == 2 {
 			return NewGoTemplatePrinterWithFuncs(ov[1], specs.GoTemplateFuncMap)
@@ -94,7 +120,22 @@ func PrinterFromFlag(flagvalue string, specs *Specs) (ValuePrinter, error) {
 		}
 		return NewGoTemplatePrinterWithFuncs(string(tpl), specs.GoTemplateFuncMap)
 	case "json":
-		return NewJSONPrinter()
+		options := ""
+		if len(ov) == 2 {
+			options = ov[1]
+		}
+		p, err := NewJSONPrinterWithOptions(options)
+		if err != nil {
+			return nil, err
+		}
+		p.(*JSONPrinter).List = specs.List
+		return p, nil
+	case "jsonl":
+		options := "lines"
+		if len(ov) == 2 && ov[1] != "" {
+			options += "," + ov[1]
+		}
+		return NewJSONPrinterWithOptions(options)
 	case "jsonpath":
 		if len(ov) != 2 {
 			return nil, fmt.Errorf("missing JSONPath expression")
@@ -115,15 +156,32 @@ func PrinterFromFlag(flagvalue string, specs *Specs) (ValuePrinter, error) {
 		}
 		return NewJSONPathPrinter(sc.Text())
 	case "yaml":
-		return NewYAMLPrinter()
+		return &YAMLPrinter{List: specs.List}, nil
+	case "yaml-stream":
+		return NewYAMLStreamPrinter()
 	}
 	// Unsupported/unknown output format.
 	wide := ""
-	if specs.WideColumnSpec != "" {
+	if specs.WideColumnSpec != "" || specs.ColumnsFromStruct != nil {
 		wide = " 'wide',"
 	}
 	return nil, fmt.Errorf("unexpected output format %q, expected "+
 		"'custom-columns', 'custom-columns-file', "+
 		"'go-template', 'go-template-file', "+
-		"'json', 'jsonpath', 'jsonpath-file',%s or 'yaml'", ov[0], wide)
+		"'json', 'jsonl', 'jsonpath', 'jsonpath-file',%s 'yaml', or 'yaml-stream'", ov[0], wide)
+}
+
+// colorize applies the color-related specs to a newly created custom-columns
+// printer, passing through any printer creation error.
+func (s *Specs) colorize(p ValuePrinter, err error) (ValuePrinter, error) {
+	if err != nil {
+		return nil, err
+	}
+	ccp := p.(*CustomColumnsPrinter)
+	ccp.Color = s.Color
+	ccp.HeaderColor = s.HeaderColor
+	for _, column := range ccp.Columns {
+		column.Colors = s.ColumnColors[column.Header]
+	}
+	return ccp, nil
 }
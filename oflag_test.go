@@ -66,6 +66,32 @@ Foo! <none>
     "Foo": "Foo!"
 }
 `)
+		PrinterPass(GoodPrinter(PrinterFromFlag("json=compact", nil)), foo, `{"Foo":"Foo!"}
+`)
+		PrinterPass(GoodPrinter(PrinterFromFlag("json=indent=2,nonewline", nil)), foo, `{
+  "Foo": "Foo!"
+}`)
+		BadPrinter(PrinterFromFlag("json=indent", nil))
+		BadPrinter(PrinterFromFlag("json=pretty", nil))
+	})
+
+	It("-o json and -o yaml with List objects", func() {
+		specs := &Specs{List: &ListEnvelope{}}
+		PrinterPass(GoodPrinter(PrinterFromFlag("json=compact", specs)), []Foo{foo},
+			`{"apiVersion":"v1","kind":"List","items":[{"Foo":"Foo!"}],"metadata":{}}`+"\n")
+		PrinterPass(GoodPrinter(PrinterFromFlag("json=compact", specs)), foo,
+			`{"Foo":"Foo!"}`+"\n")
+		PrinterPass(GoodPrinter(PrinterFromFlag("yaml", specs)), []Foo{foo},
+			"apiVersion: v1\nitems:\n- Foo: Foo!\nkind: List\nmetadata: {}\n")
+		BadPrinter(PrinterFromFlag("json=pretty", specs))
+	})
+
+	It("-o jsonl", func() {
+		PrinterPass(GoodPrinter(PrinterFromFlag("jsonl", nil)), []Foo{foo, foo},
+			"{\"Foo\":\"Foo!\"}\n{\"Foo\":\"Foo!\"}\n")
+		PrinterPass(GoodPrinter(PrinterFromFlag("jsonl=sortkeys", nil)), []Foo{foo},
+			"{\"Foo\":\"Foo!\"}\n")
+		BadPrinter(PrinterFromFlag("jsonl=pretty", nil))
 	})
 
 	It("-o jsonpath", func() {
@@ -89,6 +115,11 @@ Foo! <none>
 `)
 	})
 
+	It("-o yaml-stream", func() {
+		PrinterPass(GoodPrinter(PrinterFromFlag("yaml-stream", &Specs{List: &ListEnvelope{}})), []Foo{foo, foo},
+			"Foo: Foo!\n---\nFoo: Foo!\n")
+	})
+
 	It("-o go-template", func() {
 		BadPrinter(PrinterFromFlag(`go-template={{oops}}`, nil))
 		PrinterPass(GoodPrinter(PrinterFromFlag(`go-template`, nil)), nil,
@@ -108,4 +139,33 @@ Foo! <none>
 			"ok")
 	})
 
+	It("colors custom columns", func() {
+		specs := &Specs{
+			Color:        ColorAlways,
+			HeaderColor:  Bold,
+			ColumnColors: map[string][]ColorRule{"FOO": {{Value: "Foo!", Color: Red}}},
+		}
+		PrinterPass(GoodPrinter(PrinterFromFlag("custom-columns=FOO:Foo", specs)), []Foo{foo},
+			"\x1b[1mFOO\x1b[0m\n\x1b[31mFoo!\x1b[0m\n")
+		PrinterPass(GoodPrinter(PrinterFromFlag("custom-columns-file=./testdata/foobar.columns", specs)), []Foo{foo},
+			"\x1b[1mFOO\x1b[0m  \x1b[1mBAR\x1b[0m\n\x1b[31mFoo!\x1b[0m <none>\n")
+		BadPrinter(PrinterFromFlag("custom-columns=FOO", specs))
+	})
+
+	It("derives columns from structs", func() {
+		type Bar struct {
+			Foo string
+			Bar string `klo:",wide"`
+		}
+		bar := []Bar{{Foo: "Foo!", Bar: "Bar!"}}
+		specs := &Specs{ColumnsFromStruct: Bar{}}
+		PrinterPass(GoodPrinter(PrinterFromFlag("", specs)), bar, `FOO
+Foo!
+`)
+		PrinterPass(GoodPrinter(PrinterFromFlag("wide", specs)), bar, `FOO  BAR
+Foo! Bar!
+`)
+		BadPrinter(PrinterFromFlag("", &Specs{ColumnsFromStruct: 42}))
+	})
+
 })
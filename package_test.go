@@ -16,6 +16,8 @@ package klo
 
 import (
 	"bytes"
+	"errors"
+	"io"
 	"testing"
 
 	. "github.com/onsi/ginkgo/v2"
@@ -51,3 +53,27 @@ func GoodPrinter(p ValuePrinter, err error) ValuePrinter {
 func BadPrinter(p ValuePrinter, err error) {
 	ExpectWithOffset(1, err).Should(HaveOccurred(), "printer creation should not have succeeded")
 }
+
+// failingWriter fails writing after n successful writes.
+type failingWriter struct {
+	n int
+}
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	if w.n == 0 {
+		return 0, errors.New("failing writer")
+	}
+	w.n--
+	return len(p), nil
+}
+
+// capturingPrinter remembers the last value it was asked to print, so tests
+// can check what printers pass on down their chains.
+type capturingPrinter struct {
+	v interface{}
+}
+
+func (p *capturingPrinter) Fprint(w io.Writer, v interface{}) error {
+	p.v = v
+	return nil
+}
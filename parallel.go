@@ -0,0 +1,58 @@
+// Copyright 2019 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package klo
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// parallelFor calls fn for consecutive ranges [lo, hi) covering the indices
+// from 0 to n, with up to workers goroutines calling fn in parallel. If fn
+// fails for any ranges, then parallelFor returns the error of the first such
+// range.
+func parallelFor(n, workers int, fn func(lo, hi int) error) error {
+	if workers <= 1 || n <= 1 {
+		return fn(0, n)
+	}
+	// Hand out smaller ranges than strictly necessary, so that workers
+	// finishing early can pick up more work.
+	size := max(1, (n+8*workers-1)/(8*workers))
+	ranges := (n + size - 1) / size
+	errs := make([]error, ranges)
+	var next atomic.Int64
+	var wg sync.WaitGroup
+	for range min(workers, ranges) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				r := int(next.Add(1) - 1)
+				if r >= ranges {
+					return
+				}
+				lo := r * size
+				errs[r] = fn(lo, min(lo+size, n))
+			}
+		}()
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,159 @@
+// Copyright 2019 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package klo
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"slices"
+	"sync/atomic"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/client-go/util/jsonpath"
+)
+
+var _ = Describe("parallel evaluation", func() {
+
+	type prow struct {
+		Name string
+		Size int
+	}
+
+	rows := func(n int) []prow {
+		rows := make([]prow, n)
+		for idx := range rows {
+			rows[idx] = prow{Name: fmt.Sprintf("row-%d", (idx*7919)%n), Size: (idx * 104729) % 97}
+		}
+		return rows
+	}
+
+	// sequential prints v using p first one row after another, and then
+	// again using workers goroutines, returning both outputs.
+	sequential := func(p ValuePrinter, setWorkers func(int), v interface{}) (string, string) {
+		var seq, par bytes.Buffer
+		setWorkers(0)
+		Expect(p.Fprint(&seq, v)).To(Succeed())
+		setWorkers(4)
+		Expect(p.Fprint(&par, v)).To(Succeed())
+		return seq.String(), par.String()
+	}
+
+	It("evaluates rows in batches, keeping their order", func() {
+		columns := Columns{{Name: "name"}}
+		Expect(columns[0].SetExpression("{.Name}")).To(Succeed())
+		for _, n := range []int{0, 1, 4*rowBatch - 1, 4 * rowBatch, 10*rowBatch + 3} {
+			items := rows(n)
+			var names []string
+			Expect(columns.rows(slices.Values(items), 4, func(row Row) error {
+				names = append(names, row.Cells[0].Text)
+				return nil
+			})).To(Succeed())
+			Expect(names).To(HaveLen(n))
+			for idx, name := range names {
+				Expect(name).To(Equal(items[idx].Name))
+			}
+		}
+	})
+
+	It("stops evaluating rows on errors", func() {
+		columns := Columns{{Name: "name"}}
+		Expect(columns[0].SetExpression("{.Name}")).To(Succeed())
+		produced, consumed := 0, 0
+		Expect(columns.rows(func(yield func(prow) bool) {
+			for _, item := range rows(100 * rowBatch) {
+				produced++
+				if !yield(item) {
+					return
+				}
+			}
+		}, 2, func(row Row) error {
+			consumed++
+			if consumed == 10 {
+				return errors.New("consume error")
+			}
+			return nil
+		})).To(MatchError("consume error"))
+		Expect(consumed).To(Equal(10))
+		Expect(produced).To(BeNumerically("<=", 2*rowBatch))
+	})
+
+	It("loops over index ranges in parallel", func() {
+		for _, workers := range []int{0, 1, 3, 8} {
+			for _, n := range []int{0, 1, 2, 17, 1000} {
+				hits := make([]atomic.Int64, n)
+				Expect(parallelFor(n, workers, func(lo, hi int) error {
+					for idx := lo; idx < hi; idx++ {
+						hits[idx].Add(1)
+					}
+					return nil
+				})).To(Succeed())
+				for idx := range hits {
+					Expect(hits[idx].Load()).To(Equal(int64(1)), "workers %d, n %d", workers, n)
+				}
+			}
+		}
+		Expect(parallelFor(1000, 4, func(lo, hi int) error {
+			if lo <= 500 && 500 < hi {
+				return fmt.Errorf("range at %d", lo)
+			}
+			if lo >= 900 {
+				return errors.New("later range")
+			}
+			return nil
+		})).To(MatchError(HavePrefix("range at")))
+	})
+
+	It("prints custom columns in parallel, keeping the order of rows", func() {
+		p := GoodPrinter(NewCustomColumnsPrinterFromSpec("NAME:{.Name},SIZE:{.Size},EXPR:{.Name}"))
+		ccp := p.(*CustomColumnsPrinter)
+		// Also evaluate a column without a simple field path.
+		ccp.Columns[2].Template = jsonpath.New("expr")
+		Expect(ccp.Columns[2].Template.Parse("{.Name}-{.Size}")).To(Succeed())
+		workers := func(n int) { ccp.Workers = n }
+		items := rows(1000)
+
+		seq, par := sequential(p, workers, items)
+		Expect(par).To(Equal(seq))
+		seq, par = sequential(p, workers, slices.Values(items))
+		Expect(par).To(Equal(seq))
+		ccp.Renderer = &MarkdownRenderer{}
+		seq, par = sequential(p, workers, items)
+		Expect(par).To(Equal(seq))
+
+		ccp.Renderer = nil
+		ccp.Columns[1].Template = jsonpath.New("zero")
+		PrinterFail(p, items)
+		PrinterFail(p, slices.Values(items))
+	})
+
+	It("evaluates sort keys in parallel", func() {
+		p := GoodPrinter(NewSortingPrinter("{.Size},{.Name}",
+			GoodPrinter(NewCustomColumnsPrinterFromSpec("NAME:{.Name},SIZE:{.Size}"))))
+		sp := p.(*SortingPrinter)
+		workers := func(n int) { sp.Workers = n }
+		items := rows(1000)
+
+		seq, par := sequential(p, workers, items)
+		Expect(par).To(Equal(seq))
+		seq, par = sequential(p, workers, slices.Values(items))
+		Expect(par).To(Equal(seq))
+
+		sp.SortExpr = jsonpath.New("zero")
+		PrinterFail(p, items)
+	})
+
+})
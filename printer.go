@@ -18,6 +18,10 @@ import "io"
 
 // ValuePrinter neatly prints values (especially slices of structs) to a
 // writer, applying printer-specific formatting.
+//
+// All ValuePrinters of this package are safe for concurrent use: the same
+// printer can print values from multiple goroutines at the same time, as
+// long as it doesn't get reconfigured meanwhile.
 type ValuePrinter interface {
 	Fprint(w io.Writer, v interface{}) error
 }
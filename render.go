@@ -0,0 +1,179 @@
+// Copyright 2019 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package klo
+
+import (
+	"encoding/csv"
+	"html"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+// TableRenderer renders evaluated tables to writers.
+type TableRenderer interface {
+	Render(w io.Writer, t *Table) error
+}
+
+// TextRenderer renders tables as plain text with aligned columns, the same
+// way CustomColumnsPrinter does. If the writer is a tabwriter, then it is
+// the caller's responsibility to flush the tabwriter.
+type TextRenderer struct {
+	Padding     int       // Padding between columns.
+	Color       ColorMode // Color mode, defaulting to coloring only terminal output.
+	HeaderColor Color     // Optional color for the column headers.
+}
+
+// Render renders the table t as aligned text to the writer w.
+func (r *TextRenderer) Render(w io.Writer, t *Table) error {
+	colored := r.Color.enabled(w)
+	var rw rowWriter
+	if tw, ok := w.(*tabwriter.Writer); ok {
+		rw = &tabRowWriter{w: tw}
+	} else {
+		rw = newAlignedRowWriter(w, layoutOf(t.Columns, r.Padding))
+	}
+	if t.Headers != nil {
+		if err := rw.writeRow(headerTexts(t.Headers, r.HeaderColor, colored)); err != nil {
+			return err
+		}
+	}
+	for _, row := range t.Rows {
+		if err := rw.writeRow(row.texts(t.Columns, colored)); err != nil {
+			return err
+		}
+	}
+	return rw.flush()
+}
+
+// MarkdownRenderer renders tables as GitHub-flavored Markdown tables.
+// Right-aligned columns are marked as such. As Markdown tables always need
+// a header row, tables without headers get a header row of empty cells.
+type MarkdownRenderer struct{}
+
+// markdownEscaper escapes cell texts so that they don't break out of their
+// cells and aren't taken as inline HTML.
+var markdownEscaper = strings.NewReplacer(
+	"|", `\|`, "&", "&amp;", "<", "&lt;", ">", "&gt;", "\r\n", "<br>", "\n", "<br>")
+
+// Render renders the table t as a Markdown table to the writer w.
+func (r *MarkdownRenderer) Render(w io.Writer, t *Table) error {
+	var sb strings.Builder
+	writeRow := func(cells []string) {
+		sb.WriteString("|")
+		for _, cell := range cells {
+			sb.WriteString(" " + markdownEscaper.Replace(cell) + " |")
+		}
+		sb.WriteByte('\n')
+	}
+	headers := t.Headers
+	if headers == nil {
+		headers = make([]string, len(t.Columns))
+	}
+	writeRow(headers)
+	sb.WriteString("|")
+	for cidx := range headers {
+		if cidx < len(t.Columns) && t.Columns[cidx].Align == AlignRight {
+			sb.WriteString(" ---: |")
+		} else {
+			sb.WriteString(" --- |")
+		}
+	}
+	sb.WriteByte('\n')
+	for _, row := range t.Rows {
+		writeRow(row.plainTexts())
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// CSVRenderer renders tables as comma-separated values, see also RFC 4180.
+type CSVRenderer struct {
+	Comma rune // Optional field delimiter; defaults to ",".
+}
+
+// Render renders the table t as comma-separated values to the writer w.
+func (r *CSVRenderer) Render(w io.Writer, t *Table) error {
+	cw := csv.NewWriter(w)
+	if r.Comma != 0 {
+		cw.Comma = r.Comma
+	}
+	if t.Headers != nil {
+		if err := cw.Write(t.Headers); err != nil {
+			return err
+		}
+	}
+	for _, row := range t.Rows {
+		if err := cw.Write(row.plainTexts()); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// HTMLRenderer renders tables as HTML table elements. Right-aligned columns
+// get their cells styled accordingly.
+type HTMLRenderer struct{}
+
+// Render renders the table t as an HTML table element to the writer w.
+func (r *HTMLRenderer) Render(w io.Writer, t *Table) error {
+	var sb strings.Builder
+	writeRow := func(tag string, cells []string) {
+		sb.WriteString("<tr>")
+		for cidx, cell := range cells {
+			sb.WriteString("<" + tag)
+			if cidx < len(t.Columns) && t.Columns[cidx].Align == AlignRight {
+				sb.WriteString(` style="text-align: right"`)
+			}
+			sb.WriteString(">" + html.EscapeString(cell) + "</" + tag + ">")
+		}
+		sb.WriteString("</tr>\n")
+	}
+	sb.WriteString("<table>\n")
+	if t.Headers != nil {
+		sb.WriteString("<thead>\n")
+		writeRow("th", t.Headers)
+		sb.WriteString("</thead>\n")
+	}
+	sb.WriteString("<tbody>\n")
+	for _, row := range t.Rows {
+		writeRow("td", row.plainTexts())
+	}
+	sb.WriteString("</tbody>\n</table>\n")
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// headerTexts returns the header texts, optionally colored.
+func headerTexts(headers []string, color Color, colored bool) []string {
+	texts := make([]string, len(headers))
+	for idx, header := range headers {
+		texts[idx] = header
+		if colored {
+			texts[idx] = color.Sprint(header)
+		}
+	}
+	return texts
+}
+
+// plainTexts returns the plain cell texts of this row.
+func (r Row) plainTexts() []string {
+	texts := make([]string, len(r.Cells))
+	for cidx, cell := range r.Cells {
+		texts[cidx] = cell.Text
+	}
+	return texts
+}
@@ -0,0 +1,243 @@
+// Copyright 2019 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package klo
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+// rowWriter writes table rows, consisting of one cell text per column.
+type rowWriter interface {
+	writeRow(cells []string) error
+	flush() error
+}
+
+// tabRowWriter writes rows as lines of tab-separated cells to a tabwriter
+// that is owned by the caller and thus isn't flushed by us.
+type tabRowWriter struct {
+	w *tabwriter.Writer
+}
+
+func (t *tabRowWriter) writeRow(cells []string) error {
+	_, err := fmt.Fprintln(t.w, strings.Join(cells, "\t"))
+	return err
+}
+
+func (t *tabRowWriter) flush() error { return nil }
+
+// minColumnWidth is the minimum width of a column, including padding, so that
+// we render exactly the same tables as a tabwriter with a minwidth of 5 did.
+const minColumnWidth = 5
+
+// alignedRowWriter buffers rows until flushed and then writes them with their
+// columns aligned. In contrast to a tabwriter, it calculates the cell widths
+// based on their display widths, so it correctly skips any ANSI escape
+// sequences. Additionally, it supports right-aligned columns.
+type alignedRowWriter struct {
+	w      io.Writer
+	layout columnLayout
+	rows   [][]string
+}
+
+func newAlignedRowWriter(w io.Writer, layout columnLayout) *alignedRowWriter {
+	return &alignedRowWriter{w: w, layout: layout}
+}
+
+func (a *alignedRowWriter) writeRow(cells []string) error {
+	a.rows = append(a.rows, cells)
+	return nil
+}
+
+// flush writes all buffered rows.
+func (a *alignedRowWriter) flush() error {
+	widths := a.layout.columnWidths(a.rows)
+	var sb strings.Builder
+	for _, row := range a.rows {
+		a.layout.formatRow(&sb, row, widths)
+	}
+	a.rows = nil
+	_, err := io.WriteString(a.w, sb.String())
+	return err
+}
+
+// streamingRowWriter writes rows as soon as it knows the column widths,
+// without buffering the whole table. The column widths are taken from the
+// declared column widths, as well as from an initial sample of rows. Cells
+// overflowing their column widths afterwards are handled according to the
+// overflow policy, except for the last cell of a row, which never causes any
+// misalignment.
+type streamingRowWriter struct {
+	w        io.Writer
+	layout   columnLayout
+	samples  int        // number of rows to sample before writing.
+	overflow Overflow   // how to handle overflowing cells.
+	rows     [][]string // sampled rows.
+	widths   []int      // column widths, once known.
+}
+
+func newStreamingRowWriter(w io.Writer, layout columnLayout, samples int, overflow Overflow) *streamingRowWriter {
+	return &streamingRowWriter{w: w, layout: layout, samples: samples, overflow: overflow}
+}
+
+func (s *streamingRowWriter) writeRow(cells []string) error {
+	if s.widths == nil {
+		s.rows = append(s.rows, cells)
+		if len(s.rows) < s.samples {
+			return nil
+		}
+		return s.flush()
+	}
+	for cidx := 0; cidx < len(cells)-1 && cidx < len(s.widths); cidx++ {
+		w := displayWidth(cells[cidx]) + s.layout.padding
+		if w <= s.widths[cidx] {
+			continue
+		}
+		switch s.overflow {
+		case OverflowTruncate:
+			cells[cidx] = truncate(cells[cidx], s.widths[cidx]-s.layout.padding)
+		case OverflowWiden:
+			s.widths[cidx] = w
+		}
+	}
+	var sb strings.Builder
+	s.layout.formatRow(&sb, cells, s.widths)
+	_, err := io.WriteString(s.w, sb.String())
+	return err
+}
+
+// sampleMore limits sampling to the rows sampled so far plus n more rows,
+// writing the sampled rows right away if n is zero. Rows already written
+// don't get sampled again.
+func (s *streamingRowWriter) sampleMore(n int) error {
+	if s.widths != nil {
+		return nil
+	}
+	if s.samples = len(s.rows) + n; n > 0 {
+		return nil
+	}
+	return s.flush()
+}
+
+// flush writes any rows sampled so far, fixing the column widths.
+func (s *streamingRowWriter) flush() error {
+	if s.widths != nil {
+		return nil
+	}
+	s.widths = s.layout.columnWidths(s.rows)
+	var sb strings.Builder
+	for _, row := range s.rows {
+		s.layout.formatRow(&sb, row, s.widths)
+	}
+	s.rows = nil
+	_, err := io.WriteString(s.w, sb.String())
+	return err
+}
+
+// Overflow specifies how to handle cells overflowing their column widths when
+// streaming tables.
+type Overflow int
+
+// Supported overflow policies.
+const (
+	OverflowShift    Overflow = iota // shift the following cells of the same row.
+	OverflowTruncate                 // truncate overflowing cells.
+	OverflowWiden                    // widen the column for this and all following rows.
+)
+
+// columnLayout describes how to lay out the columns of a table.
+type columnLayout struct {
+	padding int         // padding between columns.
+	aligns  []Alignment // alignments of the columns.
+	widths  []int       // declared column display widths, 0 if undeclared.
+}
+
+// layoutOf returns the layout of the specified columns.
+func layoutOf(columns []*Column, padding int) columnLayout {
+	layout := columnLayout{
+		padding: padding,
+		aligns:  make([]Alignment, len(columns)),
+		widths:  make([]int, len(columns)),
+	}
+	for idx, column := range columns {
+		layout.aligns[idx] = column.Align
+		layout.widths[idx] = column.Width
+	}
+	return layout
+}
+
+// declared returns true if any column has a declared width.
+func (l columnLayout) declared() bool {
+	for _, w := range l.widths {
+		if w > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// align returns the alignment of the column with the specified index.
+func (l columnLayout) align(cidx int) Alignment {
+	if cidx < len(l.aligns) {
+		return l.aligns[cidx]
+	}
+	return AlignLeft
+}
+
+// columnWidths returns the widths of the columns, including padding, for the
+// specified rows. The columns are at least as wide as their declared widths.
+func (l columnLayout) columnWidths(rows [][]string) []int {
+	widths := make([]int, len(l.widths))
+	for cidx, w := range l.widths {
+		widths[cidx] = max(minColumnWidth, w+l.padding)
+	}
+	for _, row := range rows {
+		for cidx, cell := range row {
+			if cidx == len(widths) {
+				widths = append(widths, minColumnWidth)
+			}
+			if w := displayWidth(cell) + l.padding; w > widths[cidx] {
+				widths[cidx] = w
+			}
+		}
+	}
+	return widths
+}
+
+// formatRow formats a single row using the specified column widths. Similar
+// to a tabwriter, the last cell of each row isn't padded on its right side.
+// Right-aligned cells get padded on their left side so that they line up
+// with the widest cell in their column; the column padding still separates
+// them from the next column. Cells overflowing their column width still get
+// separated by the column padding from the next column.
+func (l columnLayout) formatRow(sb *strings.Builder, row []string, widths []int) {
+	for cidx, cell := range row {
+		fill := l.padding
+		if cidx < len(widths) {
+			fill = max(widths[cidx]-displayWidth(cell), l.padding)
+		}
+		if l.align(cidx) == AlignRight {
+			sb.WriteString(strings.Repeat(" ", fill-l.padding))
+			fill = l.padding
+		}
+		sb.WriteString(cell)
+		if cidx < len(row)-1 {
+			sb.WriteString(strings.Repeat(" ", fill))
+		}
+	}
+	sb.WriteByte('\n')
+}
@@ -15,156 +15,528 @@
 package klo
 
 import (
+	"cmp"
 	"errors"
+	"fmt"
 	"io"
 	"reflect"
-	"sort"
+	"slices"
+	"strings"
 
-	"github.com/fvbommel/sortorder"
 	"k8s.io/client-go/util/jsonpath"
 )
 
-// SortingPrinter sorts slice values first, before it writes them to the
-// next printer in the chain.
+// SortingPrinter sorts collection values first, such as slices, arrays, and
+// the items of Kubernetes-style List objects, before it writes them to the
+// next printer in the chain. List objects keep their wrapper, so JSON and
+// YAML output still show the List object, just with its items sorted. Map
+// values get sorted into slices of map entries, see also MapEntry. Streams
+// of items get collected into a slice first, as sorting needs to see all
+// items. Items get sorted by their first sort key, with any further sort
+// keys breaking ties in turn; items with equal sort keys keep their original
+// order. Items with missing or nil sort key values sort last, or optionally
+// first, regardless of the sort direction.
 type SortingPrinter struct {
-	ChainedPrinter ValuePrinter       // Next ValuePrinter we chain to.
-	SortExpr       *jsonpath.JSONPath // Compiled JSONPath expression.
-	raw            string             // Original JSONPath expression, to ease debugging.
+	ChainedPrinter ValuePrinter // Next ValuePrinter we chain to.
+	// Compiled JSONPath expression of the first sort key.
+	SortExpr *jsonpath.JSONPath
+	// Sort keys referencing columns by their headers sort by the formatted
+	// cell texts instead of the raw column values.
+	Formatted bool
+	// Where to sort items with missing or nil sort key values.
+	Nulls NullsOrder
+	// How to order strings, unless sort keys specify their own collations.
+	Collation Collation
+	// Number of goroutines evaluating the sort keys of items in parallel; 0
+	// or 1 evaluates sort keys one item after another.
+	Workers int
+	raw     string    // Original JSONPath expression, to ease debugging.
+	first   sortKey   // First sort key, except for its expression (SortExpr).
+	thenBy  []sortKey // Further sort keys for breaking ties.
 }
 
-// NewSortingPrinter returns a printer for outputting values in YAML format.
+// NullsOrder specifies where to sort items with missing or nil sort key
+// values.
+type NullsOrder int
+
+// Supported orders of items with missing or nil sort key values.
+const (
+	NullsLast  NullsOrder = iota // sort items with missing values last.
+	NullsFirst                   // sort items with missing values first.
+)
+
+// sortKey is a single sort key with its own sort direction.
+type sortKey struct {
+	expr       *jsonpath.JSONPath // Compiled JSONPath expression.
+	fields     *fieldPath         // Faster evaluation of field paths, if any.
+	ranged     *rangedExpr        // Expression with range blocks, if any.
+	byKey      bool               // Sort map entries by their keys.
+	descending bool               // Sort in descending order.
+	column     *Column            // Column referenced by header, if any.
+	compare    *comparator        // Comparator referenced by name, if any.
+	collation  Collation          // Collation of strings, if collated is set.
+	collated   bool               // Collation specified by this sort key.
+}
+
+// NewSortingPrinter returns a printer that sorts values according to the
+// specified JSONPath expression before passing them on to the next printer.
+// Map values can be sorted by their keys using the "{$key}" pseudo
+// expression. Multiple sort keys are separated by commas, such as in
+// "{.Namespace},-{.Created}", where a leading "-" sorts in descending order
+// by that particular key, and an optional leading "+" in ascending order.
+// Sort keys might reference a comparator by name using a trailing ":name",
+// such as in "{.Mem}:quantity"; see also RegisterComparator. Otherwise, sort
+// key values get compared using the comparator registered for their type, if
+// any, see also RegisterTypeComparator. Instead of a comparator, sort keys
+// might specify how to order strings by a trailing ":natural" (the default,
+// see also SortingPrinter.Collation), ":lexical", ":nocase", or ":unicode",
+// such as in "{.ID}:lexical"; see also Collation.
+//
+// The sort key expressions accept the same relaxed JSONPath expression
+// syntax as custom columns do, such as ".Name" or "Name". Additionally, if
+// the chained printer is (or ends in) a CustomColumnsPrinter, then sort keys
+// can reference columns by their (case-insensitive) header texts, such as
+// "AGE". Such sort keys sort by the raw column values or, optionally, by the
+// formatted cell texts, see SortingPrinter.Formatted.
 func NewSortingPrinter(expr string, p ValuePrinter) (ValuePrinter, error) {
-	jp := jsonpath.New("sort")
-	if err := jp.Parse(expr); err != nil {
-		return nil, err
+	var keys []sortKey
+	table := tablePrinter(p)
+	for _, keyexpr := range splitSortKeys(expr) {
+		key, err := newSortKey(keyexpr, table)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
 	}
 	if p == nil {
 		return nil, errors.New("nil ValuePrint to chain (hint: we cannot)")
 	}
 	return &SortingPrinter{
 		ChainedPrinter: p,
-		SortExpr:       jp,
+		SortExpr:       keys[0].expr,
 		raw:            expr,
+		first:          keys[0],
+		thenBy:         keys[1:],
 	}, nil
 }
 
+// newSortKey returns a new sort key for the specified (relaxed) JSONPath
+// expression or column header text of the table printer, if any, with an
+// optional leading "-" or "+" for the sort direction, and an optional
+// trailing ":name" referencing either a collation or a comparator. Empty
+// expressions as well as empty comparator names are rejected.
+func newSortKey(expr string, table *CustomColumnsPrinter) (sortKey, error) {
+	key := sortKey{}
+	keyexpr := expr
+	if parts := splitTopLevel(expr, ':'); len(parts) > 1 {
+		name := parts[len(parts)-1]
+		if name == "" {
+			return sortKey{}, fmt.Errorf(
+				"empty sort key comparator or collation in %q", keyexpr)
+		}
+		if collation, err := ParseCollation(name); err == nil {
+			key.collation, key.collated = collation, true
+		} else if key.compare = lookupComparator(name); key.compare == nil {
+			return sortKey{}, fmt.Errorf(
+				"unknown sort key comparator or collation %q", name)
+		}
+		expr = strings.Join(parts[:len(parts)-1], ":")
+	}
+	if strings.HasPrefix(expr, "-") {
+		key.descending = true
+		expr = expr[1:]
+	} else {
+		expr = strings.TrimPrefix(expr, "+")
+	}
+	if expr == "" {
+		return sortKey{}, fmt.Errorf("empty sort key expression in %q", keyexpr)
+	}
+	if table != nil {
+		for _, column := range table.Columns {
+			if strings.EqualFold(column.Header, expr) {
+				key.expr = column.Template
+				key.fields = column.fields
+				key.ranged = column.ranged
+				key.byKey = column.isKey
+				key.column = column
+				return key, nil
+			}
+		}
+	}
+	key.expr = jsonpath.New("sort").AllowMissingKeys(true)
+	key.byKey = expr == keyExpr || "{"+expr+"}" == keyExpr
+	if key.byKey {
+		return key, nil
+	}
+	// Relaxed expressions get normalized first, while more complex
+	// expressions, such as "{.A}{'/'}{.B}", get parsed as they are.
+	if relaxed, err := relaxedExpression(expr); err == nil {
+		expr = relaxed
+	}
+	if err := key.expr.Parse(expr); err != nil {
+		return sortKey{}, err
+	}
+	key.fields = newFieldPath(key.expr, expr)
+	key.ranged = newRangedExpr(key.expr, "sort", expr)
+	return key, nil
+}
+
+// splitSortKeys splits a sort expression into its individual sort key
+// expressions at commas, except for commas inside curly braces, brackets, or
+// quotes, so sort keys can still contain JSONPath unions.
+func splitSortKeys(expr string) []string {
+	return splitTopLevel(expr, ',')
+}
+
+// splitTopLevel splits an expression at the separator, except for
+// separators inside curly braces, brackets, or quotes.
+func splitTopLevel(expr string, sep rune) []string {
+	keys := []string{}
+	depth := 0
+	var quote rune
+	start := 0
+	for idx, ch := range expr {
+		switch {
+		case quote != 0:
+			if ch == quote {
+				quote = 0
+			}
+		case ch == '\'' || ch == '"':
+			quote = ch
+		case ch == '{' || ch == '[':
+			depth++
+		case ch == '}' || ch == ']':
+			depth--
+		case ch == sep && depth == 0:
+			keys = append(keys, expr[start:idx])
+			start = idx + 1
+		}
+	}
+	return append(keys, expr[start:])
+}
+
+// keys returns all sort keys of this sorting printer.
+func (sp *SortingPrinter) keys() []sortKey {
+	first := sp.first
+	first.expr = sp.SortExpr
+	return append([]sortKey{first}, sp.thenBy...)
+}
+
 // Fprint first sorts values according to a JSONPath expression used for
 // sorting, then chains to the next ValuePrinter for printing.
 func (sp *SortingPrinter) Fprint(w io.Writer, v interface{}) error {
-	val := reflect.ValueOf(v)
-	if val.Kind() == reflect.Ptr {
-		val = val.Elem()
-	}
-	if val.Kind() != reflect.Slice {
+	// Streams of items need to be collected in full before they can be
+	// sorted.
+	v = collect(v)
+	val, ok := collection(v)
+	if !ok {
+		if !val.IsValid() {
+			return sp.ChainedPrinter.Fprint(w, v)
+		}
 		return sp.ChainedPrinter.Fprint(w, val.Interface())
 	}
-	//
+	keys := sp.keys()
+	nkeys := len(keys)
 	slicelen := val.Len()
-	index := keyedItems{
-		keys:  make([]reflect.Value, slicelen),
-		items: make([]reflect.Value, slicelen),
+	// Evaluate the sort keys of all items only once, up front, precomputing
+	// their values so that comparing items later rarely needs reflection.
+	values := make([]sortValue, slicelen*nkeys)
+	collations := make([]Collation, nkeys)
+	for kidx, key := range keys {
+		collations[kidx] = sp.Collation
+		if key.collated {
+			collations[kidx] = key.collation
+		}
 	}
-	for idx := 0; idx < slicelen; idx++ {
-		index.items[idx] = val.Index(idx)
-		key, err := sp.SortExpr.FindResults(index.items[idx].Interface())
-		if err != nil {
-			return err
+	err := parallelFor(slicelen, sp.Workers, func(lo, hi int) error {
+		for idx := lo; idx < hi; idx++ {
+			it := item(val, idx)
+			for kidx, key := range keys {
+				keyval, err := sp.keyValue(key, it)
+				if err != nil {
+					return err
+				}
+				sv := &values[idx*nkeys+kidx]
+				*sv = sortValueOf(keyval)
+				sv.setComparator(key.compare)
+				sv.setCollation(collations[kidx])
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	// Sort a permutation of the item indices instead of the items
+	// themselves, so we never modify the caller's collection. Items with
+	// equal keys get ordered by their original positions, so sorting is
+	// stable.
+	nullsFirst := sp.Nulls == NullsFirst
+	perm := make([]int, slicelen)
+	for idx := range perm {
+		perm[idx] = idx
+	}
+	slices.SortFunc(perm, func(i, j int) int {
+		vi, vj := values[i*nkeys:(i+1)*nkeys], values[j*nkeys:(j+1)*nkeys]
+		for kidx, key := range keys {
+			c := compareSortValues(&vi[kidx], &vj[kidx],
+				key.descending, nullsFirst)
+			if c != 0 {
+				return c
+			}
 		}
-		// Depending on the JSONPath expression, the key for this item (column)
-		// might consist of multiple values, or even none at all.
-		if len(key) == 0 || len(key[0]) == 0 {
-			index.keys[idx] = reflect.ValueOf("<none>")
-		} else if len(key) == 1 && len(key[0]) == 1 {
-			index.keys[idx] = key[0][0]
-		} else {
-			index.keys[idx] = reflect.ValueOf(stringFromJSONExprResult(key, ""))
+		return cmp.Compare(i, j)
+	})
+	// That's it: hand over the sorted items in a new collection to the
+	// chained printer so it can carry out its part of the job.
+	sliceType := val.Type()
+	if sliceType.Kind() != reflect.Slice {
+		sliceType = reflect.SliceOf(sliceType.Elem())
+	}
+	sorted := reflect.MakeSlice(sliceType, slicelen, slicelen)
+	for idx, pos := range perm {
+		sorted.Index(idx).Set(val.Index(pos))
+	}
+	return sp.ChainedPrinter.Fprint(w, reshaped(v, sorted))
+}
+
+// keyValue returns the value of the sort key for the specified item. Missing
+// values are returned as the zero reflect.Value.
+func (sp *SortingPrinter) keyValue(
+	key sortKey, it interface{},
+) (reflect.Value, error) {
+	if key.column != nil && sp.Formatted {
+		cell := key.column.Cell(it)
+		if cell.Err != nil || len(cell.Values) == 0 {
+			return reflect.Value{}, cell.Err
 		}
+		return reflect.ValueOf(cell.Text), nil
+	}
+	res, err := findResults(key.expr, key.fields, key.ranged, key.byKey, it)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+	return sortKeyValue(res), nil
+}
+
+// sortKeyValue returns the sort key value for the result of evaluating a
+// sort key's JSONPath expression. Missing values are returned as the zero
+// reflect.Value.
+func sortKeyValue(res [][]reflect.Value) reflect.Value {
+	// Depending on the JSONPath expression, the key for this item (column)
+	// might consist of multiple values, or even none at all.
+	if len(res) == 0 || len(res[0]) == 0 {
+		return reflect.Value{}
+	} else if len(res) == 1 && len(res[0]) == 1 {
+		return res[0][0]
 	}
-	sort.Sort(index)
-	// That's it: hand over the sorted items to the chained printer so it can
-	// carry out its part of the job.
-	return sp.ChainedPrinter.Fprint(w, index.items)
-}
-
-// keyedItems represents the complete index for sorting, that is, the index keys
-// for each row, as well as the row values themselved. For simplicity, we go for
-// two separate slices for keys and values respectively, instead of a single
-// slice of key-value struct.
-type keyedItems struct {
-	keys  []reflect.Value // results of evaluating JSONPath expressions.
-	items []reflect.Value // references the items slice to be sorted.
-}
-
-// Returns number of items (interface sort.Interface).
-func (ki keyedItems) Len() int { return len(ki.keys) }
-
-// Compares two items by their keys (interface sort.Interface).
-func (ki keyedItems) Less(i, j int) bool { return reflectedLess(ki.keys[i], ki.keys[j]) }
-
-// Swaps two child nodes (interface sort.Interface). Swapping the item
-// references that are in the form of reflect.Values is unfortunately slightly
-// involved, as a simple swap without an intermediate temporary would fail.
-func (ki keyedItems) Swap(i, j int) {
-	ki.keys[i], ki.keys[j] = ki.keys[j], ki.keys[i]
-	// We cannot simply swap two elements in a slice if they are more
-	// intricate types, such as strings, interfaces, maps, et cetera, as
-	// opposed to ints. See also: https://github.com/golang/go/issues/3126
-	// Instead, we need to dance around and sacrifice the Go(ds) of
-	// reflection.
-	temp := reflect.New(ki.items[i].Type()).Elem()
-	temp.Set(ki.items[i])
-	ki.items[i].Set(ki.items[j])
-	ki.items[j].Set(temp)
-}
-
-// reflectedLess compares two values and returns true if i<j. In case of
-// incompatible values, it resorts to comparing the string representations of
-// the values instead. Since we have no idea as how to define a sorting order on
-// arrays, slices, structs, et cetera, we just consider them incompatible too,
-// and resort to sorting their string representation, whatever sense that might
-// make.
+	return reflect.ValueOf(stringFromJSONExprResult(res, ""))
+}
+
+// sortValue is a sort key value, precomputed for comparing it quickly.
+type sortValue struct {
+	val     reflect.Value // value, with pointers followed; invalid if nil.
+	rank    int           // kind rank of the value.
+	class   numberClass   // class of numbers and booleans.
+	i       int64         // signed ints, as well as booleans as 0 or 1.
+	u       uint64        // unsigned ints.
+	f       float64       // floats.
+	s       string        // strings, as their collation keys.
+	collate Collation     // collation of strings.
+	compare *comparator   // comparator, if any.
+	iface   interface{}   // value prepared for the comparator.
+}
+
+// numberClass distinguishes signed ints, unsigned ints, and floats.
+type numberClass int
+
+const (
+	classInt numberClass = iota
+	classUint
+	classFloat
+)
+
+// sortValueOf returns the sort value for the specified value.
+func sortValueOf(v reflect.Value) sortValue {
+	v = indirect(v)
+	sv := sortValue{val: v, rank: kindRank(v)}
+	switch sv.rank {
+	case rankBool:
+		if v.Bool() {
+			sv.i = 1
+		}
+	case rankNumber:
+		switch {
+		case v.CanInt():
+			sv.i = v.Int()
+		case v.CanUint():
+			sv.class, sv.u = classUint, v.Uint()
+		default:
+			sv.class, sv.f = classFloat, v.Float()
+		}
+	case rankString:
+		sv.s = v.String()
+	}
+	return sv
+}
+
+// setComparator sets the comparator referenced by name, if any, or
+// otherwise the comparator registered for the type of the sort value, if
+// any.
+func (sv *sortValue) setComparator(named *comparator) {
+	if !sv.val.IsValid() || !sv.val.CanInterface() {
+		return
+	}
+	sv.compare = named
+	if named == nil {
+		sv.compare = lookupTypeComparator(sv.val.Type())
+	}
+	if sv.compare != nil {
+		sv.iface = sv.compare.prepare(sv.val.Interface())
+	}
+}
+
+// setCollation sets the collation to order string values with, replacing
+// string values with their collation keys.
+func (sv *sortValue) setCollation(c Collation) {
+	sv.collate = c
+	if sv.rank == rankString {
+		sv.s = c.key(sv.s)
+	}
+}
+
+// compareSortValues compares two sort values in the specified direction,
+// with nil values sorting either first or last, regardless of the direction.
+func compareSortValues(a, b *sortValue, descending bool, nullsFirst bool) int {
+	anull, bnull := a.rank == rankNil, b.rank == rankNil
+	if anull || bnull {
+		switch {
+		case anull == bnull:
+			return 0
+		case anull == nullsFirst:
+			return -1
+		}
+		return 1
+	}
+	c := a.compareTo(b)
+	if descending {
+		return -c
+	}
+	return c
+}
+
+// compareTo compares this sort value to another sort value, using a
+// comparator if referenced by name, or if the same comparator is registered
+// for the types of both values. Otherwise, it compares the values according
+// to the total order defined by compareReflected.
+func (sv *sortValue) compareTo(other *sortValue) int {
+	if sv.compare != nil && sv.compare == other.compare {
+		return sv.compare.prepared(sv.iface, other.iface)
+	}
+	if sv.rank != other.rank {
+		return cmp.Compare(sv.rank, other.rank)
+	}
+	switch sv.rank {
+	case rankNil:
+		return 0
+	case rankBool:
+		return cmp.Compare(sv.i, other.i)
+	case rankNumber:
+		return sv.compareNumber(other)
+	case rankString:
+		return sv.collate.compare(sv.s, other.s)
+	}
+	c := strings.Compare(sv.val.Type().String(), other.val.Type().String())
+	if c != 0 {
+		return c
+	}
+	return naturalCompare(valueString(sv.val), valueString(other.val))
+}
+
+// compareNumber compares two numbers of any int, uint, or float kinds,
+// without losing precision when comparing ints with uints.
+func (sv *sortValue) compareNumber(other *sortValue) int {
+	if sv.class == classFloat || other.class == classFloat {
+		return cmp.Compare(sv.float(), other.float())
+	}
+	switch {
+	case sv.class == classInt && other.class == classInt:
+		return cmp.Compare(sv.i, other.i)
+	case sv.class == classUint && other.class == classUint:
+		return cmp.Compare(sv.u, other.u)
+	case sv.class == classInt: // ...and other is a uint.
+		if sv.i < 0 {
+			return -1
+		}
+		return cmp.Compare(uint64(sv.i), other.u)
+	}
+	// sv is a uint and other is an int.
+	return -other.compareNumber(sv)
+}
+
+// float returns the number as a float64.
+func (sv *sortValue) float() float64 {
+	switch sv.class {
+	case classInt:
+		return float64(sv.i)
+	case classUint:
+		return float64(sv.u)
+	}
+	return sv.f
+}
+
+// reflectedLess compares two values and returns true if i<j, according to
+// the total order defined by compareReflected.
 //
 // Oh, and in contrast to kubectl's isLess() version, we don't panic, because
 // that's really not nice in the face of CLI users.
 func reflectedLess(i, j reflect.Value) bool {
-	// First, follow pointers, so we don't need to care about them later...
-	for i.Kind() == reflect.Ptr {
-		i = i.Elem()
-	}
-	for j.Kind() == reflect.Ptr {
-		j = j.Elem()
-	}
-	// Now let's compare...
-	switch i.Kind() {
-	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-		switch j.Kind() {
-		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-			return i.Int() < j.Int()
-		case reflect.Float32, reflect.Float64:
-			return float64(i.Int()) < j.Float()
-		}
-	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-		switch j.Kind() {
-		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-			return i.Uint() < j.Uint()
-		case reflect.Float32, reflect.Float64:
-			return float64(i.Uint()) < j.Float()
-		}
-	case reflect.Float32, reflect.Float64:
-		switch j.Kind() {
-		case reflect.Float32, reflect.Float64:
-			return i.Float() < j.Float()
-		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-			return i.Float() < float64(j.Int())
-		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-			return i.Float() < float64(j.Uint())
-		}
+	return compareReflected(i, j) < 0
+}
+
+// compareReflected compares two values, defining a total order across all
+// kinds of values: nil values come first, followed by booleans, then numbers
+// (regardless of whether they are ints, uints, or floats), then strings (in
+// natural sort order), and finally all other values. Since we have no idea
+// as how to define a sorting order on arrays, slices, structs, et cetera, we
+// order them by their type names, and then by their string representations,
+// whatever sense that might make.
+func compareReflected(i, j reflect.Value) int {
+	si, sj := sortValueOf(i), sortValueOf(j)
+	return si.compareTo(&sj)
+}
+
+// Kind ranks defining the order between values of different kinds.
+const (
+	rankNil = iota
+	rankBool
+	rankNumber
+	rankString
+	rankOther
+)
+
+// kindRank returns the rank of the kind of the specified value.
+func kindRank(v reflect.Value) int {
+	switch v.Kind() {
+	case reflect.Invalid:
+		return rankNil
+	case reflect.Bool:
+		return rankBool
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return rankNumber
 	case reflect.String:
-		if j.Kind() == reflect.String {
-			return sortorder.NaturalLess(i.String(), j.String())
-		}
+		return rankString
+	}
+	return rankOther
+}
+
+// valueString returns the string representation of a value.
+func valueString(v reflect.Value) string {
+	if v.CanInterface() {
+		return fmt.Sprintf("%v", v.Interface())
 	}
-	// We've fallen through because the two values to be compared are of
-	// incompatible types, so let's compare their stringified values instead.
-	return sortorder.NaturalLess(reflect.ValueOf(i).String(), reflect.ValueOf(j).String())
+	return v.String()
 }
@@ -0,0 +1,94 @@
+// Copyright 2019 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package klo
+
+import (
+	"fmt"
+	"io"
+	"math/rand"
+	"testing"
+)
+
+// discardPrinter discards all values to be printed, so that benchmarks
+// measure only the printers chaining to it.
+type discardPrinter struct{}
+
+func (discardPrinter) Fprint(w io.Writer, v interface{}) error { return nil }
+
+// benchmarkProcess is a typical item of large process lists.
+type benchmarkProcess struct {
+	PID     int
+	Name    string
+	Cmdline []string
+	Mem     string
+}
+
+// benchmarkProcesses returns n processes in random order.
+func benchmarkProcesses(n int) []benchmarkProcess {
+	rng := rand.New(rand.NewSource(42))
+	procs := make([]benchmarkProcess, n)
+	for idx := range procs {
+		pid := rng.Intn(4 * n)
+		procs[idx] = benchmarkProcess{
+			PID:     pid,
+			Name:    fmt.Sprintf("process-%d", pid%1000),
+			Cmdline: []string{"/usr/bin/foo", "--bar"},
+			Mem:     fmt.Sprintf("%dMi", rng.Intn(4096)),
+		}
+	}
+	return procs
+}
+
+func benchmarkSortingPrinter(b *testing.B, expr string, n int, fieldPaths bool) {
+	procs := benchmarkProcesses(n)
+	sp, err := NewSortingPrinter(expr, discardPrinter{})
+	if err != nil {
+		b.Fatal(err)
+	}
+	if !fieldPaths {
+		sorter := sp.(*SortingPrinter)
+		sorter.first.fields = nil
+		for idx := range sorter.thenBy {
+			sorter.thenBy[idx].fields = nil
+		}
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := sp.Fprint(io.Discard, procs); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkSortingPrinterInt(b *testing.B) {
+	benchmarkSortingPrinter(b, "{.PID}", 200000, true)
+}
+
+func BenchmarkSortingPrinterString(b *testing.B) {
+	benchmarkSortingPrinter(b, "{.Name}", 200000, true)
+}
+
+func BenchmarkSortingPrinterMultiKey(b *testing.B) {
+	benchmarkSortingPrinter(b, "{.Name},-{.PID}", 200000, true)
+}
+
+func BenchmarkSortingPrinterQuantity(b *testing.B) {
+	benchmarkSortingPrinter(b, "{.Mem}:quantity", 200000, true)
+}
+
+func BenchmarkSortingPrinterIntJSONPath(b *testing.B) {
+	benchmarkSortingPrinter(b, "{.PID}", 200000, false)
+}
@@ -15,8 +15,11 @@
 package klo
 
 import (
+	"bytes"
 	"fmt"
+	"io"
 	"reflect"
+	"slices"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -87,6 +90,61 @@ var _ = Describe("-o output options", func() {
 		}
 	})
 
+	It("orders values of mixed kinds totally", func() {
+		type tpoint struct{ X, Y int }
+		var nilptr *int
+		one := 1
+		vals := []interface{}{
+			nil, false, true, int8(-1), uint64(0), &one, 1.5, uint8(2), int64(3),
+			"", "a2", "a10", "b",
+			[]int{1}, []int{2}, tpoint{X: 1}, tpoint{X: 2},
+		}
+		for i := range vals {
+			for j := range vals {
+				Expect(reflectedLess(reflect.ValueOf(vals[i]), reflect.ValueOf(vals[j]))).To(
+					Equal(i < j), "%#v < %#v", vals[i], vals[j])
+			}
+		}
+		Expect(compareReflected(reflect.ValueOf(nilptr), reflect.ValueOf(nil))).To(BeZero())
+		Expect(compareReflected(reflect.ValueOf(uint64(1<<63)), reflect.ValueOf(int64(-1)))).To(Equal(1))
+		Expect(compareReflected(reflect.ValueOf(uint64(1<<63)), reflect.ValueOf(int64(1<<62)))).To(Equal(1))
+		Expect(compareReflected(reflect.ValueOf(int64(1<<62)), reflect.ValueOf(uint64(1<<63)))).To(Equal(-1))
+	})
+
+	It("sorts missing and nil keys first or last", func() {
+		type row struct {
+			Name string
+			Size *int
+		}
+		one, two := 1, 2
+		table := func() []row {
+			return []row{
+				{Name: "a"},
+				{Name: "b", Size: &two},
+				{Name: "c"},
+				{Name: "d", Size: &one},
+			}
+		}
+		ccp := GoodPrinter(NewCustomColumnsPrinterFromSpec("NAME:{.Name}"))
+		sp := GoodPrinter(NewSortingPrinter("{.Size}", ccp))
+		PrinterPass(sp, table(), "NAME\nd\nb\na\nc\n")
+		sp = GoodPrinter(NewSortingPrinter("-{.Size}", ccp))
+		PrinterPass(sp, table(), "NAME\nb\nd\na\nc\n")
+		sp.(*SortingPrinter).Nulls = NullsFirst
+		PrinterPass(sp, table(), "NAME\na\nc\nb\nd\n")
+
+		// Keys missing from map entries, and mixed kinds of keys.
+		sp = GoodPrinter(NewSortingPrinter("{.size}", ccp))
+		mixed := []map[string]interface{}{
+			{"Name": "a", "size": "big"},
+			{"Name": "b"},
+			{"Name": "c", "size": 42},
+			{"Name": "d", "size": true},
+			{"Name": "e", "size": "<none>"},
+		}
+		PrinterPass(sp, mixed, "NAME\nd\nc\ne\na\nb\n")
+	})
+
 	It("sorts before printing", func() {
 		type row struct {
 			A string
@@ -140,6 +198,249 @@ foo  <none>
 `)
 	})
 
+	It("sorts without modifying its input", func() {
+		type row struct {
+			A string
+			B int
+		}
+		table := []row{
+			{A: "foo", B: 2},
+			{A: "bar", B: 1},
+			{A: "baz", B: 1},
+		}
+		original := slices.Clone(table)
+		PrinterPass(GoodPrinter(NewSortingPrinter("{.B}", &JSONPrinter{})), table, `[
+    {
+        "A": "bar",
+        "B": 1
+    },
+    {
+        "A": "baz",
+        "B": 1
+    },
+    {
+        "A": "foo",
+        "B": 2
+    }
+]
+`)
+		Expect(table).To(Equal(original))
+	})
+
+	It("sorts by multiple keys", func() {
+		type row struct {
+			NS   string
+			Name string
+			Age  int
+		}
+		table := func() []row {
+			return []row{
+				{NS: "kube", Name: "foo", Age: 1},
+				{NS: "default", Name: "bar", Age: 2},
+				{NS: "kube", Name: "baz", Age: 3},
+				{NS: "default", Name: "qux", Age: 2},
+				{NS: "kube", Name: "zoo", Age: 3},
+			}
+		}
+		BadPrinter(NewSortingPrinter("{.NS},{.Age", nil))
+		BadPrinter(NewSortingPrinter("{.NS},-{.Age}", nil))
+		ccp := GoodPrinter(NewCustomColumnsPrinterFromSpec("NS:{.NS},NAME:{.Name},AGE:{.Age}"))
+		PrinterPass(GoodPrinter(NewSortingPrinter("{.NS},-{.Age}", ccp)), table(),
+			`NS      NAME AGE
+default bar  2
+default qux  2
+kube    baz  3
+kube    zoo  3
+kube    foo  1
+`)
+		PrinterPass(GoodPrinter(NewSortingPrinter("-{.NS},+{.Age},-{.Name}", ccp)), table(),
+			`NS      NAME AGE
+kube    foo  1
+kube    zoo  3
+kube    baz  3
+default qux  2
+default bar  2
+`)
+		// Ties keep their original order, even when sorting in descending
+		// order.
+		PrinterPass(GoodPrinter(NewSortingPrinter("-{.Age}", ccp)), table(),
+			`NS      NAME AGE
+kube    baz  3
+kube    zoo  3
+default bar  2
+default qux  2
+kube    foo  1
+`)
+		Expect(splitSortKeys("{.a['x','y']},-{.b[1,2]},{','}")).To(Equal(
+			[]string{"{.a['x','y']}", "-{.b[1,2]}", "{','}"}))
+	})
+
+	It("sorts by relaxed expressions and column headers", func() {
+		type row struct {
+			Name string
+			Size int
+		}
+		table := []row{
+			{Name: "foo", Size: 9},
+			{Name: "bar", Size: 10},
+			{Name: "baz", Size: 100},
+		}
+		ccp := GoodPrinter(NewCustomColumnsPrinterFromSpec("NAME:{.Name},SIZE:{.Size}"))
+		for _, expr := range []string{"Name", ".Name", "{Name}", "{.Name}", "NAME", "name"} {
+			var out bytes.Buffer
+			Expect(GoodPrinter(NewSortingPrinter(expr, ccp)).Fprint(&out, table)).To(Succeed())
+			Expect(out.String()).To(Equal("NAME SIZE\nbar  10\nbaz  100\nfoo  9\n"), "sort key %q", expr)
+		}
+		BadPrinter(NewSortingPrinter("NAME", nil))
+		BadPrinter(NewSortingPrinter("{Name", ccp))
+
+		// Sort by the raw column values, or alternatively by the formatted
+		// cell texts, which sort differently here.
+		ccp.(*CustomColumnsPrinter).Columns[1].Formatter = func(v interface{}) string {
+			return fmt.Sprintf("%x", v)
+		}
+		sp := GoodPrinter(NewSortingPrinter("-SIZE", ccp))
+		PrinterPass(sp, table, "NAME SIZE\nbaz  64\nbar  a\nfoo  9\n")
+		sp.(*SortingPrinter).Formatted = true
+		PrinterPass(sp, table, "NAME SIZE\nbar  a\nbaz  64\nfoo  9\n")
+
+		// Column headers get resolved through chains of printers.
+		fp := GoodPrinter(NewFilteringPrinter([]string{"{.Size}>9"}, ccp))
+		PrinterPass(GoodPrinter(NewSortingPrinter("SIZE", fp)), table,
+			"NAME SIZE\nbar  a\nbaz  64\n")
+
+		// Without a table printer, header texts are just relaxed JSONPath
+		// expressions.
+		sp = GoodPrinter(NewSortingPrinter("NAME", &JSONPrinter{}))
+		Expect(sp.(*SortingPrinter).first.column).To(BeNil())
+		Expect(sp.(*SortingPrinter).SortExpr).NotTo(BeIdenticalTo(
+			ccp.(*CustomColumnsPrinter).Columns[0].Template))
+
+		sp = GoodPrinter(NewSortingPrinter("$key", ccp))
+		PrinterPass(sp, map[string]row{"y": table[0], "x": table[1]}, "NAME SIZE\nbar  a\nfoo  9\n")
+
+		ccp.(*CustomColumnsPrinter).Columns[0].Template = jsonpath.New("zero")
+		sp = GoodPrinter(NewSortingPrinter("NAME", ccp))
+		sp.(*SortingPrinter).Formatted = true
+		PrinterFail(sp, table)
+	})
+
+	It("sorts strings by collations", func() {
+		type row struct {
+			ID string
+		}
+		table := []row{{ID: "a10"}, {ID: "B"}, {ID: "a9"}, {ID: "b"}}
+		ccp := GoodPrinter(NewCustomColumnsPrinterFromSpec("ID:{.ID}"))
+		PrinterPass(GoodPrinter(NewSortingPrinter("ID", ccp)), table, "ID\nB\na9\na10\nb\n")
+		PrinterPass(GoodPrinter(NewSortingPrinter("ID:lexical", ccp)), table, "ID\nB\na10\na9\nb\n")
+		PrinterPass(GoodPrinter(NewSortingPrinter("-{.ID}:nocase", ccp)), table, "ID\nB\nb\na10\na9\n")
+		PrinterPass(GoodPrinter(NewSortingPrinter("ID:unicode", ccp)), table, "ID\na10\na9\nb\nB\n")
+
+		sp := GoodPrinter(NewSortingPrinter("ID", ccp))
+		sp.(*SortingPrinter).Collation = CollationNoCase
+		PrinterPass(sp, table, "ID\na9\na10\nB\nb\n")
+		sp = GoodPrinter(NewSortingPrinter("ID:natural", ccp))
+		sp.(*SortingPrinter).Collation = CollationNoCase
+		PrinterPass(sp, table, "ID\nB\na9\na10\nb\n")
+
+		BadPrinter(NewSortingPrinter("ID:random", ccp))
+	})
+
+	It("rejects empty sort keys", func() {
+		ccp := GoodPrinter(NewCustomColumnsPrinterFromSpec("A:{.A}"))
+		for _, expr := range []string{"", "-", "+", "{.A},", ",{.A}", "{.A},,{.B}", ":lexical"} {
+			_, err := NewSortingPrinter(expr, ccp)
+			Expect(err).To(MatchError(ContainSubstring("empty sort key expression")), "%q", expr)
+		}
+		for _, expr := range []string{"{.A}:", "-A:"} {
+			_, err := NewSortingPrinter(expr, ccp)
+			Expect(err).To(MatchError(ContainSubstring("empty sort key comparator or collation")), "%q", expr)
+		}
+	})
+
+	It("sorts maps", func() {
+		type row struct {
+			A string
+			B int
+		}
+		table := map[string]row{
+			"x": {A: "foo", B: 666},
+			"y": {A: "bar", B: 42},
+			"z": {A: "aaa", B: 420},
+		}
+		BadPrinter(NewSortingPrinter("{$key}", nil))
+		ccp := GoodPrinter(NewCustomColumnsPrinterFromSpec("KEY:{$key},A:{.A},B:{.B}"))
+		PrinterPass(GoodPrinter(NewSortingPrinter("{.B}", ccp)), table,
+			`KEY  A    B
+y    bar  42
+z    aaa  420
+x    foo  666
+`)
+		PrinterPass(GoodPrinter(NewSortingPrinter("{$key}", ccp)), &table,
+			`KEY  A    B
+x    foo  666
+y    bar  42
+z    aaa  420
+`)
+		PrinterPass(GoodPrinter(NewSortingPrinter("{$key}", ccp)), []row{{A: "foo"}},
+			`KEY    A    B
+<none> foo  0
+`)
+	})
+
+	It("sorts arrays and List objects, keeping their shapes", func() {
+		type items []tlistitem
+		type arraylist struct {
+			Items [3]tlistitem
+		}
+		arr := [3]tlistitem{{A: "foo"}, {A: "bar"}, {A: "baz"}}
+		sorted := [3]tlistitem{{A: "bar"}, {A: "baz"}, {A: "foo"}}
+		for _, tt := range []struct {
+			v        interface{}
+			expected interface{}
+		}{
+			{arr, sorted},
+			{&arr, sorted},
+			{items(arr[:]), items(sorted[:])},
+			{tlist{Kind: "List", Items: arr[:]}, tlist{Kind: "List", Items: sorted[:]}},
+			{&tlist{Kind: "List", Items: arr[:]}, tlist{Kind: "List", Items: sorted[:]}},
+			{arraylist{Items: arr}, arraylist{Items: sorted}},
+		} {
+			cp := &capturingPrinter{}
+			sp := GoodPrinter(NewSortingPrinter("{.A}", cp))
+			Expect(sp.Fprint(io.Discard, tt.v)).To(Succeed())
+			Expect(cp.v).To(Equal(tt.expected), "%T", tt.v)
+		}
+		Expect(arr[0].A).To(Equal("foo"))
+
+		PrinterPass(GoodPrinter(NewSortingPrinter("-{.A}", &JSONPrinter{})),
+			&tlist{Kind: "List", Items: arr[:2]}, `{
+    "Kind": "List",
+    "Items": [
+        {
+            "A": "foo"
+        },
+        {
+            "A": "bar"
+        }
+    ]
+}
+`)
+	})
+
+	It("sorts streams", func() {
+		type row struct {
+			A string
+		}
+		ccp := GoodPrinter(NewCustomColumnsPrinterFromSpec("A:{.A}"))
+		PrinterPass(GoodPrinter(NewSortingPrinter("{.A}", ccp)),
+			slices.Values([]row{{A: "foo"}, {A: "bar"}}),
+			`A
+bar
+foo
+`)
+	})
+
 	It("simply passes on non-sliced things", func() {
 		r := struct {
 			A string
@@ -177,7 +478,7 @@ foo  42
 			{},
 		}
 		ccp := GoodPrinter(NewCustomColumnsPrinterFromSpec("A:{.A[*]}"))
-		PrinterPass(GoodPrinter(NewSortingPrinter("", ccp)), &table,
+		PrinterPass(GoodPrinter(NewSortingPrinter("{.A[*]}", ccp)), &table,
 			`A
 <none>
 `)
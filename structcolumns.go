@@ -0,0 +1,149 @@
+// Copyright 2019 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package klo
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// NewCustomColumnsPrinterFromStruct returns a new custom columns printer with
+// its columns derived from the exported fields of a struct type, given either
+// as a value of that struct type, a pointer to it, a slice of it, or as its
+// reflect.Type. If wide is false, then fields tagged as "wide" are skipped.
+//
+// The column headers default to the upper-cased field names. Struct fields
+// can be further customized using "klo" field tags in the form of
+// `klo:"<header>,<option>..."`, with the following options:
+//   - wide: only show this column in the wide view.
+//   - align=left|right: alignment of the column's cells.
+//   - format=<formatter-name>: name of a registered Formatter, such as "age".
+//
+// A field tagged `klo:"-"` doesn't get a column. Fields of embedded structs
+// are flattened into the columns of the embedding struct.
+func NewCustomColumnsPrinterFromStruct(v interface{}, wide bool) (ValuePrinter, error) {
+	t, ok := v.(reflect.Type)
+	if !ok {
+		if v == nil {
+			return nil, errors.New("no struct type given")
+		}
+		t = reflect.TypeOf(v)
+	}
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("expected struct type, got %s", t)
+	}
+	ccp := &CustomColumnsPrinter{
+		Padding: 1,
+	}
+	if err := ccp.addStructColumns(t, "", wide, map[reflect.Type]bool{}); err != nil {
+		return nil, err
+	}
+	if len(ccp.Columns) == 0 {
+		return nil, fmt.Errorf("struct type %s has no columns", t)
+	}
+	return ccp, nil
+}
+
+// addStructColumns adds the columns for the fields of the specified struct
+// type, with path being the JSONPath to the struct from the row object.
+// Embedding contains the struct types embedding the specified struct type on
+// the current path, so that embedding cycles get rejected instead of
+// recursing endlessly.
+func (p *CustomColumnsPrinter) addStructColumns(t reflect.Type, path string, wide bool, embedding map[reflect.Type]bool) error {
+	embedding[t] = true
+	defer delete(embedding, t)
+	for idx := 0; idx < t.NumField(); idx++ {
+		field := t.Field(idx)
+		tag, tagged := field.Tag.Lookup("klo")
+		if tag == "-" {
+			continue
+		}
+		fieldpath := path + "." + field.Name
+		if field.Anonymous && !tagged {
+			ft := field.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				if embedding[ft] {
+					return fmt.Errorf("field %s: struct type %s embeds itself", field.Name, ft)
+				}
+				if err := p.addStructColumns(ft, fieldpath, wide, embedding); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		cc, widecol, err := structColumn(field, tag)
+		if err != nil {
+			return err
+		}
+		if widecol && !wide {
+			continue
+		}
+		cc.Name = fmt.Sprintf("column%d", len(p.Columns)+1)
+		if err := cc.SetExpression("{" + fieldpath + "}"); err != nil {
+			return err
+		}
+		p.Columns = append(p.Columns, cc)
+	}
+	return nil
+}
+
+// structColumn returns a new column for the specified struct field, as well
+// as whether the column belongs to the wide view only. The column's JSONPath
+// expression still needs to be set.
+func structColumn(field reflect.StructField, tag string) (*Column, bool, error) {
+	opts := strings.Split(tag, ",")
+	cc := &Column{
+		Header: opts[0],
+	}
+	if cc.Header == "" {
+		cc.Header = strings.ToUpper(field.Name)
+	}
+	wide := false
+	for _, opt := range opts[1:] {
+		name, value, _ := strings.Cut(opt, "=")
+		switch name {
+		case "wide":
+			wide = true
+		case "align":
+			switch value {
+			case "left":
+				cc.Align = AlignLeft
+			case "right":
+				cc.Align = AlignRight
+			default:
+				return nil, false, fmt.Errorf("field %s: unexpected alignment %q, expected 'left' or 'right'",
+					field.Name, value)
+			}
+		case "format":
+			if cc.Formatter = LookupFormatter(value); cc.Formatter == nil {
+				return nil, false, fmt.Errorf("field %s: unknown formatter %q", field.Name, value)
+			}
+		default:
+			return nil, false, fmt.Errorf("field %s: unexpected klo tag option %q", field.Name, opt)
+		}
+	}
+	return cc, wide, nil
+}
@@ -0,0 +1,163 @@
+// Copyright 2019 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package klo
+
+import (
+	"reflect"
+	"time"
+
+	t "github.com/thediveo/klo/testutil"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+type tmeta struct {
+	Namespace string
+	Labels    string `klo:"LBLS,wide"`
+}
+
+type tpod struct {
+	tmeta
+	Name     string
+	Restarts int       `klo:",align=right"`
+	Created  time.Time `klo:"AGE,format=age"`
+	Node     string    `klo:",wide"`
+	Secret   string    `klo:"-"`
+	internal string
+}
+
+type tbadmeta struct {
+	A int `klo:",foo"`
+}
+
+type tcycle struct {
+	*tcycle
+	Name string
+}
+
+type tindirectcycle struct {
+	tcyclemeta
+	Name string
+}
+
+type tcyclemeta struct {
+	*tindirectcycle
+	Namespace string
+}
+
+var _ = Describe("custom columns from structs", func() {
+
+	BeforeEach(func() {
+		oldnow := now
+		now = func() time.Time { return time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC) }
+		DeferCleanup(func() { now = oldnow })
+	})
+
+	pods := []tpod{
+		{
+			tmeta:    tmeta{Namespace: "default", Labels: "app=foo"},
+			Name:     "foo",
+			Restarts: 1,
+			Created:  time.Date(2024, 1, 2, 3, 0, 0, 0, time.UTC),
+			Node:     "node1",
+		},
+		{
+			tmeta:    tmeta{Namespace: "kube-system"},
+			Name:     "bar",
+			Restarts: 123,
+			Created:  time.Date(2023, 12, 20, 0, 0, 0, 0, time.UTC),
+			Node:     "node2",
+		},
+	}
+
+	It("rejects non-struct types", func() {
+		t.PassFail(t.PASSFAILS{
+			t.FAIL{"nil", t.Err(NewCustomColumnsPrinterFromStruct(nil, false))},
+			t.FAIL{"int", t.Err(NewCustomColumnsPrinterFromStruct(42, false))},
+			t.FAIL{"no columns", t.Err(NewCustomColumnsPrinterFromStruct(struct{ a int }{}, false))},
+		}) //nolint:composites
+	})
+
+	It("rejects invalid tags", func() {
+		t.PassFail(t.PASSFAILS{
+			t.FAIL{"alignment", t.Err(NewCustomColumnsPrinterFromStruct(struct {
+				A int `klo:",align=center"`
+			}{}, false))},
+			t.FAIL{"formatter", t.Err(NewCustomColumnsPrinterFromStruct(struct {
+				A int `klo:",format=unknown"`
+			}{}, false))},
+			t.FAIL{"option", t.Err(NewCustomColumnsPrinterFromStruct(struct {
+				A int `klo:",foo"`
+			}{}, false))},
+			t.FAIL{"embedded", t.Err(NewCustomColumnsPrinterFromStruct(struct {
+				*tmeta
+				B struct {
+					C int `klo:",foo"`
+				}
+				D int `klo:",foo"`
+			}{}, false))},
+			t.FAIL{"inside embedded", t.Err(NewCustomColumnsPrinterFromStruct(struct {
+				*tbadmeta
+				B int
+			}{}, false))},
+		}) //nolint:composites
+	})
+
+	It("rejects embedding cycles", func() {
+		t.PassFail(t.PASSFAILS{
+			t.FAIL{"self", t.Err(NewCustomColumnsPrinterFromStruct(tcycle{}, false))},
+			t.FAIL{"indirect", t.Err(NewCustomColumnsPrinterFromStruct(tindirectcycle{}, false))},
+		}) //nolint:composites
+		_, err := NewCustomColumnsPrinterFromStruct(tcycle{}, false)
+		Expect(err).To(MatchError(ContainSubstring("embeds itself")))
+	})
+
+	It("derives columns from struct fields", func() {
+		p := GoodPrinter(NewCustomColumnsPrinterFromStruct(reflect.TypeOf(tpod{}), false))
+		headers := []string{}
+		for _, column := range p.(*CustomColumnsPrinter).Columns {
+			headers = append(headers, column.Header)
+		}
+		Expect(headers).To(Equal([]string{"NAMESPACE", "NAME", "RESTARTS", "AGE"}))
+
+		PrinterPass(p, pods, `NAMESPACE   NAME RESTARTS AGE
+default     foo         1 4m5s
+kube-system bar       123 13d
+`)
+	})
+
+	It("derives wide columns from struct fields", func() {
+		p := GoodPrinter(NewCustomColumnsPrinterFromStruct(&pods, true))
+		PrinterPass(p, pods, `NAMESPACE   LBLS    NAME RESTARTS AGE  NODE
+default     app=foo foo         1 4m5s node1
+kube-system         bar       123 13d  node2
+`)
+	})
+
+	It("flattens embedded struct pointers", func() {
+		type row struct {
+			*tmeta
+			Name string
+		}
+		p := GoodPrinter(NewCustomColumnsPrinterFromStruct([]row{}, false))
+		PrinterPass(p, []row{{Name: "foo"}, {tmeta: &tmeta{Namespace: "default"}, Name: "bar"}},
+			`NAMESPACE NAME
+<none>    foo
+default   bar
+`)
+	})
+
+})
@@ -0,0 +1,208 @@
+// Copyright 2019 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package klo
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Table is an evaluated table, consisting of column headers and rows of
+// evaluated cells. Tables get rendered by TableRenderers; applications might
+// post-process the rows of a table before rendering it, such as coloring
+// individual cells.
+type Table struct {
+	Columns []*Column // Columns the table was evaluated from.
+	Headers []string  // Column header texts; nil if headers are hidden.
+	Rows    []Row     // Evaluated table rows.
+}
+
+// Row is an evaluated table row, consisting of one cell per column.
+type Row struct {
+	Object interface{} // Row object the cells were evaluated from.
+	Cells  []Cell      // Evaluated cells, one per column.
+}
+
+// Cell is an evaluated table cell.
+type Cell struct {
+	Values []interface{} // Raw values of the column's JSONPath expression.
+	Text   string        // Formatted cell text, without any colors.
+	Color  Color         // Color to render the cell text in, if any.
+	Err    error         // Evaluation error, if any.
+}
+
+// Columns evaluates table rows from row objects.
+type Columns []*Column
+
+// Table returns the table evaluated from the value v. Collections, such as
+// slices, arrays, maps, and Kubernetes-style List objects, as well as
+// streams of items, get evaluated into one row per item. Other values get
+// evaluated into a single row. Errors evaluating individual cells don't stop
+// evaluating the table; instead, they get recorded in the cells.
+func (c Columns) Table(v interface{}) *Table {
+	return c.table(v, 1)
+}
+
+// table returns the table evaluated from the value v, with up to workers
+// goroutines evaluating rows in parallel.
+func (c Columns) table(v interface{}, workers int) *Table {
+	t := &Table{
+		Columns: c,
+		Headers: c.Headers(),
+	}
+	_ = c.rows(v, workers, func(row Row) error {
+		t.Rows = append(t.Rows, row)
+		return nil
+	})
+	return t
+}
+
+// rowBatch is the number of row objects per worker that get evaluated in
+// parallel at a time.
+const rowBatch = 64
+
+// rows evaluates the rows of the row objects of the value v, see also Table,
+// and calls fn for each row in the order of the row objects. With more than
+// one worker, up to workers goroutines evaluate batches of rows in parallel,
+// so the rows of streams get passed to fn only after their batch is complete
+// or the stream has ended. It stops at the first error returned by fn.
+func (c Columns) rows(v interface{}, workers int, fn func(row Row) error) error {
+	if workers <= 1 {
+		return rowObjects(v, func(obj interface{}) error {
+			return fn(c.Row(obj))
+		})
+	}
+	objs := make([]interface{}, 0, rowBatch*workers)
+	rows := make([]Row, rowBatch*workers)
+	flush := func() error {
+		// Hand out ranges of rows to the workers, instead of individual
+		// rows, so workers rarely need to synchronize.
+		_ = parallelFor(len(objs), workers, func(lo, hi int) error {
+			for idx := lo; idx < hi; idx++ {
+				rows[idx] = c.Row(objs[idx])
+			}
+			return nil
+		})
+		for idx := range objs {
+			if err := fn(rows[idx]); err != nil {
+				return err
+			}
+		}
+		clear(objs)
+		clear(rows)
+		objs = objs[:0]
+		return nil
+	}
+	if err := rowObjects(v, func(obj interface{}) error {
+		if objs = append(objs, obj); len(objs) < cap(objs) {
+			return nil
+		}
+		return flush()
+	}); err != nil {
+		return err
+	}
+	return flush()
+}
+
+// Headers returns the header texts of the columns.
+func (c Columns) Headers() []string {
+	headers := make([]string, len(c))
+	for idx, column := range c {
+		headers[idx] = column.Header
+	}
+	return headers
+}
+
+// Row returns the row evaluated from the specified row object.
+func (c Columns) Row(obj interface{}) Row {
+	row := Row{
+		Object: obj,
+		Cells:  make([]Cell, len(c)),
+	}
+	for cidx, column := range c {
+		row.Cells[cidx] = column.Cell(obj)
+	}
+	return row
+}
+
+// Cell returns the cell of this column evaluated from the specified row
+// object. Cells whose evaluation failed get the text "<error>", while cells
+// without any values get the text "<none>".
+func (c *Column) Cell(obj interface{}) Cell {
+	res, err := findResults(c.Template, c.fields, c.ranged, c.isKey, obj)
+	if err != nil {
+		return Cell{Text: "<error>", Err: err}
+	}
+	// Depending on the JSONPath expression, the result for this column might
+	// consist of multiple values, or even none at all.
+	var cell Cell
+	if len(res) == 0 || len(res[0]) == 0 {
+		cell.Text = "<none>"
+	} else {
+		texts := []string{}
+		for arridx := range res {
+			for validx := range res[arridx] {
+				val := res[arridx][validx].Interface()
+				cell.Values = append(cell.Values, val)
+				if c.Formatter != nil {
+					texts = append(texts, c.Formatter(val))
+				} else {
+					texts = append(texts, fmt.Sprintf("%v", val))
+				}
+			}
+		}
+		cell.Text = strings.Join(texts, ", ")
+	}
+	cell.Color = cellColor(cell.Text, c.Colors)
+	return cell
+}
+
+// Err returns the first error of this table's cells, if any.
+func (t *Table) Err() error {
+	for _, row := range t.Rows {
+		if err := row.Err(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Err returns the first error of this row's cells, if any.
+func (r Row) Err() error {
+	for _, cell := range r.Cells {
+		if cell.Err != nil {
+			return cell.Err
+		}
+	}
+	return nil
+}
+
+// texts returns the cell texts of this row for aligned text output. Cells
+// get truncated to the maximum widths of their columns and optionally
+// colored. Please note that color rules always match the complete cell text,
+// even if it gets truncated.
+func (r Row) texts(columns []*Column, colored bool) []string {
+	texts := make([]string, len(r.Cells))
+	for cidx, cell := range r.Cells {
+		texts[cidx] = cell.Text
+		if cidx < len(columns) && columns[cidx].MaxWidth > 0 {
+			texts[cidx] = truncate(texts[cidx], columns[cidx].MaxWidth)
+		}
+		if colored {
+			texts[cidx] = cell.Color.Sprint(texts[cidx])
+		}
+	}
+	return texts
+}
@@ -0,0 +1,171 @@
+// Copyright 2019 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package klo
+
+import (
+	"bytes"
+	"fmt"
+	"text/tabwriter"
+
+	"k8s.io/client-go/util/jsonpath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("tables", func() {
+
+	type tobj struct {
+		Name  string
+		Size  int
+		Tags  []string
+		Notes string
+	}
+
+	objs := []tobj{
+		{Name: "foo", Size: 42, Tags: []string{"a", "b"}},
+		{Name: "b|r", Size: 1, Notes: "<b> & \"c\""},
+	}
+
+	columns := func() Columns {
+		ccp := GoodPrinter(NewCustomColumnsPrinterFromSpec(
+			"NAME:{.Name},SIZE:{.Size},TAGS:{.Tags[*]},NOTES:{.Notes}")).(*CustomColumnsPrinter)
+		ccp.Columns[1].Align = AlignRight
+		return ccp.Columns
+	}
+
+	It("evaluates raw values, texts and colors of cells", func() {
+		c := columns()
+		c[0].Colors = []ColorRule{{Value: "foo", Color: Red}}
+		c[1].Formatter = func(v interface{}) string { return fmt.Sprintf("#%v", v) }
+		tab := c.Table(objs)
+		Expect(tab.Headers).To(Equal([]string{"NAME", "SIZE", "TAGS", "NOTES"}))
+		Expect(tab.Rows).To(HaveLen(2))
+		Expect(tab.Err()).NotTo(HaveOccurred())
+
+		row := tab.Rows[0]
+		Expect(row.Object).To(Equal(objs[0]))
+		Expect(row.Cells[0]).To(Equal(Cell{Values: []interface{}{"foo"}, Text: "foo", Color: Red}))
+		Expect(row.Cells[1]).To(Equal(Cell{Values: []interface{}{42}, Text: "#42"}))
+		Expect(row.Cells[2]).To(Equal(Cell{Values: []interface{}{"a", "b"}, Text: "a, b"}))
+		Expect(tab.Rows[1].Cells[0].Color).To(BeEmpty())
+
+		Expect(c.Table(objs[0]).Rows).To(HaveLen(1))
+		Expect(c.Table(nil).Rows).To(BeEmpty())
+		Expect(c.Row(42).Cells[0].Text).To(Equal("<none>"))
+		Expect(c.Row(42).Cells[0].Values).To(BeNil())
+	})
+
+	It("records cell errors", func() {
+		c := columns()
+		c[2].Template = jsonpath.New("zero")
+		tab := c.Table(objs)
+		Expect(tab.Err()).To(HaveOccurred())
+		Expect(tab.Rows[0].Err()).To(HaveOccurred())
+		Expect(tab.Rows[0].Cells[2].Text).To(Equal("<error>"))
+		Expect(tab.Rows[0].Cells[0].Err).NotTo(HaveOccurred())
+	})
+
+	It("renders aligned text", func() {
+		tab := columns().Table(objs)
+		tab.Rows[1].Cells[0].Color = Green
+		var out bytes.Buffer
+		Expect((&TextRenderer{Padding: 1}).Render(&out, tab)).To(Succeed())
+		Expect(out.String()).To(Equal("NAME SIZE TAGS   NOTES\n" +
+			"foo    42 a, b   \n" +
+			"b|r     1 <none> <b> & \"c\"\n"))
+
+		out.Reset()
+		tab.Headers = nil
+		Expect((&TextRenderer{Padding: 1, Color: ColorAlways}).Render(&out, tab)).To(Succeed())
+		Expect(out.String()).To(Equal("foo    42 a, b   \n" +
+			"\x1b[32mb|r\x1b[0m     1 <none> <b> & \"c\"\n"))
+
+		out.Reset()
+		tw := tabwriter.NewWriter(&out, 5, 0, 1, ' ', 0)
+		Expect((&TextRenderer{}).Render(tw, tab)).To(Succeed())
+		Expect(out.String()).To(BeEmpty())
+		Expect(tw.Flush()).To(Succeed())
+		Expect(out.String()).To(Equal("foo  42   a, b   \n" +
+			"b|r  1    <none> <b> & \"c\"\n"))
+
+		Expect((&TextRenderer{}).Render(&failingWriter{}, tab)).NotTo(Succeed())
+	})
+
+	It("renders Markdown", func() {
+		tab := columns().Table(objs)
+		var out bytes.Buffer
+		Expect((&MarkdownRenderer{}).Render(&out, tab)).To(Succeed())
+		Expect(out.String()).To(Equal(`| NAME | SIZE | TAGS | NOTES |
+| --- | ---: | --- | --- |
+| foo | 42 | a, b |  |
+| b\|r | 1 | &lt;none&gt; | &lt;b&gt; &amp; "c" |
+`))
+
+		out.Reset()
+		tab.Headers = nil
+		tab.Rows = nil
+		Expect((&MarkdownRenderer{}).Render(&out, tab)).To(Succeed())
+		Expect(out.String()).To(Equal(`|  |  |  |  |
+| --- | ---: | --- | --- |
+`))
+	})
+
+	It("renders CSV", func() {
+		tab := columns().Table(objs)
+		var out bytes.Buffer
+		Expect((&CSVRenderer{}).Render(&out, tab)).To(Succeed())
+		Expect(out.String()).To(Equal(`NAME,SIZE,TAGS,NOTES
+foo,42,"a, b",
+b|r,1,<none>,"<b> & ""c"""
+`))
+
+		out.Reset()
+		tab.Headers = nil
+		Expect((&CSVRenderer{Comma: ';'}).Render(&out, tab)).To(Succeed())
+		Expect(out.String()).To(Equal(`foo;42;a, b;
+b|r;1;<none>;"<b> & ""c"""
+`))
+
+		Expect((&CSVRenderer{}).Render(&failingWriter{}, tab)).NotTo(Succeed())
+	})
+
+	It("renders HTML", func() {
+		tab := columns().Table(objs[1:])
+		var out bytes.Buffer
+		Expect((&HTMLRenderer{}).Render(&out, tab)).To(Succeed())
+		Expect(out.String()).To(Equal(`<table>
+<thead>
+<tr><th>NAME</th><th style="text-align: right">SIZE</th><th>TAGS</th><th>NOTES</th></tr>
+</thead>
+<tbody>
+<tr><td>b|r</td><td style="text-align: right">1</td><td>&lt;none&gt;</td><td>&lt;b&gt; &amp; &#34;c&#34;</td></tr>
+</tbody>
+</table>
+`))
+	})
+
+	It("renders custom columns using a renderer", func() {
+		p := GoodPrinter(NewCustomColumnsPrinterFromSpec("NAME:{.Name},SIZE:{.Size}"))
+		ccp := p.(*CustomColumnsPrinter)
+		ccp.Renderer = &CSVRenderer{}
+		PrinterPass(p, objs, "NAME,SIZE\nfoo,42\nb|r,1\n")
+		ccp.HideHeaders = true
+		PrinterPass(p, objs, "foo,42\nb|r,1\n")
+		ccp.Columns[0].Template = jsonpath.New("zero")
+		PrinterFail(p, objs)
+	})
+
+})
@@ -0,0 +1,324 @@
+// Copyright 2019 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package klo
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"strings"
+
+	"k8s.io/client-go/util/jsonpath"
+)
+
+// EventType is the type of a watch event, such as "ADDED".
+type EventType string
+
+// Watch event types, in the same spelling as Kubernetes uses them.
+const (
+	Added    EventType = "ADDED"
+	Modified EventType = "MODIFIED"
+	Deleted  EventType = "DELETED"
+	Bookmark EventType = "BOOKMARK"
+)
+
+// Event is a single watch event about an object having been added, modified,
+// or deleted.
+type Event struct {
+	Type   EventType
+	Object interface{}
+}
+
+// WatchPrinter prints streams of watch events as tables, similar to "kubectl
+// get -w". By default, it prints the table header only once and then appends
+// a row for each event. Similar to kubectl printing the initial list of
+// objects, the column widths get sized to fit the initial batch of events,
+// that is, the events already queued in a channel, or all events of a
+// collection. Afterwards, only declared column widths and Table.SampleRows
+// further rows size the columns, so events from iter.Seq streams, or events
+// arriving later, need declared widths or SampleRows to stay aligned.
+// Alternatively, it redraws the whole table in place for each event, using
+// ANSI cursor control sequences.
+type WatchPrinter struct {
+	Table *CustomColumnsPrinter // Table printer evaluating the columns.
+	// Compiled JSONPath expression identifying objects.
+	IdentExpr  *jsonpath.JSONPath
+	ShowEvents bool        // Show the event types in an EVENT column.
+	Redraw     bool        // Redraw the table for each event.
+	raw        string      // Original JSONPath expression, to ease debugging.
+	ranged     *rangedExpr // Expression with range blocks, if any.
+}
+
+// NewWatchPrinter returns a printer for streams of watch events that prints
+// the event objects using the specified custom columns printer. The JSONPath
+// expression identifies objects, so that updated objects replace their
+// previous rows when redrawing.
+func NewWatchPrinter(identexpr string, p ValuePrinter) (ValuePrinter, error) {
+	jp := jsonpath.New("ident").AllowMissingKeys(true)
+	if err := jp.Parse(identexpr); err != nil {
+		return nil, err
+	}
+	ccp, ok := p.(*CustomColumnsPrinter)
+	if !ok {
+		return nil, fmt.Errorf("expected custom columns printer to chain, got %T", p)
+	}
+	return &WatchPrinter{
+		Table:     ccp,
+		IdentExpr: jp,
+		raw:       identexpr,
+		ranged:    newRangedExpr(jp, "ident", identexpr),
+	}, nil
+}
+
+// Fprint prints the events from a stream (or collection) of events as they
+// arrive. When appending rows, the column widths are taken from the declared
+// column widths, the initial batch of events, and the following
+// Table.SampleRows rows, if any. Events are
+// either Event values or Kubernetes-style watch events, that is, structs with
+// a string Type field and an Object field. Any other items are taken as the
+// objects of ADDED and MODIFIED events, depending on whether an object with
+// the same identity has been seen before.
+func (p *WatchPrinter) Fprint(w io.Writer, v interface{}) error {
+	seq, _, ok := stream(v)
+	// The initial batch of events are the events already queued in a
+	// channel, or all events of a collection.
+	initial := 0
+	if val := reflect.ValueOf(v); ok && val.Kind() == reflect.Chan {
+		initial = val.Len()
+	}
+	if !ok {
+		items, ok := collection(v)
+		if !ok {
+			return errors.New("expected stream or collection of watch events")
+		}
+		initial = items.Len()
+		seq = func(yield func(reflect.Value) bool) {
+			for idx := 0; idx < items.Len(); idx++ {
+				if !yield(reflect.ValueOf(item(items, idx))) {
+					return
+				}
+			}
+		}
+	}
+	table := p.table()
+	colored := table.Color.enabled(w)
+	var rw *streamingRowWriter
+	if !p.Redraw {
+		rw = table.streamingRowWriter(w, table.layout())
+		if initial > 0 {
+			rw.samples = math.MaxInt
+		}
+		if err := table.printheaders(rw, colored); err != nil {
+			return err
+		}
+	}
+	var objects watchedObjects
+	lines := 0
+	events := 0
+	for it := range seq {
+		events++
+		ev, err := p.event(it, &objects)
+		if err != nil {
+			return err
+		}
+		if !p.Redraw {
+			if ev.Type != Bookmark {
+				entry := MapEntry{Key: string(ev.Type), Value: ev.Object}
+				if err := table.printrow(rw, entry, colored); err != nil {
+					return err
+				}
+			}
+			if events == initial {
+				if err := rw.sampleMore(table.SampleRows); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if ev.Type == Bookmark {
+			continue
+		}
+		// Redraw the whole table in place: move the cursor up to the
+		// beginning of the previously drawn table, clear the screen from
+		// there on, and then draw the updated table.
+		var sb strings.Builder
+		if lines > 0 {
+			fmt.Fprintf(&sb, "\x1b[%dA", lines)
+		}
+		sb.WriteString("\r\x1b[J")
+		arw := newAlignedRowWriter(&sb, table.layout())
+		entries := objects.list()
+		if err := table.fprint(arw, entries, colored); err != nil {
+			return err
+		}
+		if err := arw.flush(); err != nil {
+			return err
+		}
+		lines = len(entries)
+		if !table.HideHeaders {
+			lines++
+		}
+		if _, err := io.WriteString(w, sb.String()); err != nil {
+			return err
+		}
+	}
+	if rw != nil {
+		return rw.flush()
+	}
+	return nil
+}
+
+// table returns the custom columns printer to print the events with. If the
+// event types are to be shown, then it returns a copy of the custom columns
+// printer with an additional EVENT column.
+func (p *WatchPrinter) table() *CustomColumnsPrinter {
+	if !p.ShowEvents {
+		return p.Table
+	}
+	// The event rows are map entries with the event type as their key, so
+	// the EVENT column simply shows the map keys. The column is as wide as
+	// the longest event type, so appended rows stay aligned.
+	table := *p.Table
+	table.Columns = append([]*Column{{
+		Name:   "event",
+		Header: "EVENT",
+		Width:  len(Modified),
+		isKey:  true,
+	}}, p.Table.Columns...)
+	return &table
+}
+
+// event returns the watch event for the specified stream item, updating the
+// watched objects accordingly.
+func (p *WatchPrinter) event(it reflect.Value, objects *watchedObjects) (Event, error) {
+	ev := eventOf(it)
+	res, err := findTemplateResults(p.IdentExpr, p.ranged, ev.Object)
+	if err != nil {
+		return Event{}, err
+	}
+	ident := stringFromJSONExprResult(res, ",")
+	switch ev.Type {
+	case Bookmark:
+	case Deleted:
+		objects.remove(ident)
+	default:
+		if ev.Type == "" {
+			ev.Type = Added
+			if objects.has(ident) {
+				ev.Type = Modified
+			}
+		}
+		objects.upsert(ident, ev)
+	}
+	return ev, nil
+}
+
+// eventOf returns the watch event for a stream item, which either is an
+// Event, a Kubernetes-style watch event, or just a plain object. The type of
+// plain object events is left empty.
+func eventOf(it reflect.Value) Event {
+	val := indirect(it)
+	if !val.IsValid() {
+		return Event{}
+	}
+	if ev, ok := val.Interface().(Event); ok {
+		return ev
+	}
+	if val.Kind() == reflect.Struct {
+		typ := val.FieldByName("Type")
+		obj := val.FieldByName("Object")
+		if typ.IsValid() && typ.Kind() == reflect.String && obj.IsValid() && obj.CanInterface() {
+			return Event{Type: EventType(typ.String()), Object: obj.Interface()}
+		}
+	}
+	return Event{Object: val.Interface()}
+}
+
+// watchedObjects keeps track of the watched objects that haven't been deleted
+// (yet), in the order they were first seen. The objects are stored as map
+// entries with the type of their latest event as the entries' keys. Deleted
+// objects leave holes in the entries, which get compacted only once there
+// are more holes than objects, so that each event takes constant time on
+// average.
+type watchedObjects struct {
+	slots   map[string]int // indices of the objects' entries by identity.
+	idents  []string       // identities of the objects, by entry index.
+	entries []MapEntry     // entries in order, with nil keys for holes.
+	holes   int            // number of holes in the entries.
+}
+
+// has returns true if the object with the specified identity is known.
+func (o *watchedObjects) has(ident string) bool {
+	_, ok := o.slots[ident]
+	return ok
+}
+
+// upsert adds or updates the watched object with the specified identity.
+func (o *watchedObjects) upsert(ident string, ev Event) {
+	entry := MapEntry{Key: string(ev.Type), Value: ev.Object}
+	if idx, ok := o.slots[ident]; ok {
+		o.entries[idx] = entry
+		return
+	}
+	if o.slots == nil {
+		o.slots = map[string]int{}
+	}
+	o.slots[ident] = len(o.entries)
+	o.idents = append(o.idents, ident)
+	o.entries = append(o.entries, entry)
+}
+
+// remove removes the watched object with the specified identity, if known.
+func (o *watchedObjects) remove(ident string) {
+	idx, ok := o.slots[ident]
+	if !ok {
+		return
+	}
+	delete(o.slots, ident)
+	o.entries[idx] = MapEntry{}
+	o.holes++
+	if o.holes <= len(o.slots) {
+		return
+	}
+	// Compact the entries, updating the indices of the objects that moved.
+	n := 0
+	for idx, entry := range o.entries {
+		if entry.Key == nil {
+			continue
+		}
+		o.entries[n], o.idents[n] = entry, o.idents[idx]
+		o.slots[o.idents[n]] = n
+		n++
+	}
+	clear(o.entries[n:])
+	clear(o.idents[n:])
+	o.entries, o.idents, o.holes = o.entries[:n], o.idents[:n], 0
+}
+
+// list returns the entries of the watched objects, without any holes.
+func (o *watchedObjects) list() []MapEntry {
+	if o.holes == 0 {
+		return o.entries
+	}
+	entries := make([]MapEntry, 0, len(o.slots))
+	for _, entry := range o.entries {
+		if entry.Key != nil {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
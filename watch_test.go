@@ -0,0 +1,209 @@
+// Copyright 2019 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package klo
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"slices"
+
+	"k8s.io/client-go/util/jsonpath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("watch printer", func() {
+
+	type tpod struct {
+		Name   string
+		Status string
+	}
+
+	// Kubernetes-style watch event.
+	type kevent struct {
+		Type   string
+		Object interface{}
+	}
+
+	events := func() []interface{} {
+		return []interface{}{
+			Event{Type: Added, Object: tpod{Name: "foo", Status: "Pending"}},
+			&Event{Type: Added, Object: &tpod{Name: "bar", Status: "Pending"}},
+			kevent{Type: "MODIFIED", Object: tpod{Name: "foo", Status: "Running"}},
+			Event{Type: Bookmark},
+			Event{Type: Deleted, Object: tpod{Name: "bar", Status: "Pending"}},
+		}
+	}
+
+	var ccp *CustomColumnsPrinter
+
+	BeforeEach(func() {
+		ccp = GoodPrinter(NewCustomColumnsPrinterFromSpec("NAME:Name,STATUS:Status")).(*CustomColumnsPrinter)
+	})
+
+	It("rejects invalid watch printers", func() {
+		_, err := NewWatchPrinter("{.Name", ccp)
+		Expect(err).To(HaveOccurred())
+		_, err = NewWatchPrinter("{.Name}", GoodPrinter(NewJSONPrinter()))
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("appends rows", func() {
+		wp := GoodPrinter(NewWatchPrinter("{.Name}", ccp)).(*WatchPrinter)
+		PrinterPass(wp, slices.Values(events()), `NAME STATUS
+foo  Pending
+bar  Pending
+foo  Running
+bar  Pending
+`)
+		wp.ShowEvents = true
+		PrinterPass(wp, events(), `EVENT    NAME STATUS
+ADDED    foo  Pending
+ADDED    bar  Pending
+MODIFIED foo  Running
+DELETED  bar  Pending
+`)
+		Expect(wp.Table.Columns).To(HaveLen(2))
+	})
+
+	It("sizes columns to fit the initial batch of events", func() {
+		wp := GoodPrinter(NewWatchPrinter("{.Name}", ccp)).(*WatchPrinter)
+		pods := []tpod{
+			{Name: "averyveryverylongname", Status: "Pending"},
+			{Name: "b", Status: "Running"},
+			{Name: "c", Status: "Pending"},
+		}
+		expected := `NAME                  STATUS
+averyveryverylongname Pending
+b                     Running
+c                     Pending
+`
+		PrinterPass(wp, pods, expected)
+
+		// Only the events already queued get sized for, with the rows of
+		// the initial batch written before later events arrive.
+		ch := make(chan tpod, len(pods))
+		for _, pod := range pods[:2] {
+			ch <- pod
+		}
+		r, w := io.Pipe()
+		done := make(chan error, 1)
+		go func() {
+			done <- wp.Fprint(w, ch)
+			w.Close()
+		}()
+		initial := make([]byte, len(expected)-len("c                     Pending\n"))
+		_, err := io.ReadFull(r, initial)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(initial)).To(Equal(expected[:len(initial)]))
+		ch <- pods[2]
+		close(ch)
+		rest, err := io.ReadAll(r)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(initial) + string(rest)).To(Equal(expected))
+		Expect(<-done).To(Succeed())
+
+		// Streams without an initial batch need sampling.
+		ccp.SampleRows = 1
+		PrinterPass(wp, slices.Values(pods), expected)
+	})
+
+	It("derives event types for plain objects", func() {
+		wp := GoodPrinter(NewWatchPrinter("{.Name}", ccp)).(*WatchPrinter)
+		wp.ShowEvents = true
+		ccp.Columns[0].Width = 8
+		PrinterPass(wp, slices.Values([]tpod{
+			{Name: "foo", Status: "Pending"},
+			{Name: "foo", Status: "Running"},
+		}), `EVENT    NAME     STATUS
+ADDED    foo      Pending
+MODIFIED foo      Running
+`)
+	})
+
+	It("redraws the table", func() {
+		wp := GoodPrinter(NewWatchPrinter("{.Name}", ccp)).(*WatchPrinter)
+		wp.Redraw = true
+		wp.ShowEvents = true
+		PrinterPass(wp, events(), "\r\x1b[J"+
+			"EVENT    NAME STATUS\n"+
+			"ADDED    foo  Pending\n"+
+			"\x1b[2A\r\x1b[J"+
+			"EVENT    NAME STATUS\n"+
+			"ADDED    foo  Pending\n"+
+			"ADDED    bar  Pending\n"+
+			"\x1b[3A\r\x1b[J"+
+			"EVENT    NAME STATUS\n"+
+			"MODIFIED foo  Running\n"+
+			"ADDED    bar  Pending\n"+
+			"\x1b[3A\r\x1b[J"+
+			"EVENT    NAME STATUS\n"+
+			"MODIFIED foo  Running\n")
+
+		ccp.HideHeaders = true
+		wp.ShowEvents = false
+		PrinterPass(wp, events()[:2], "\r\x1b[J"+
+			"foo  Pending\n"+
+			"\x1b[1A\r\x1b[J"+
+			"foo  Pending\n"+
+			"bar  Pending\n")
+	})
+
+	It("keeps track of watched objects in order", func() {
+		var objects watchedObjects
+		for idx := range 10 {
+			objects.upsert(fmt.Sprint(idx), Event{Type: Added, Object: idx})
+		}
+		for _, idx := range []int{3, 0, 7, 9, 42, 5, 1} {
+			objects.remove(fmt.Sprint(idx))
+		}
+		objects.upsert("8", Event{Type: Modified, Object: 8})
+		objects.upsert("10", Event{Type: Added, Object: 10})
+		Expect(objects.has("8")).To(BeTrue())
+		Expect(objects.has("7")).To(BeFalse())
+		Expect(objects.list()).To(Equal([]MapEntry{
+			{Key: "ADDED", Value: 2},
+			{Key: "ADDED", Value: 4},
+			{Key: "ADDED", Value: 6},
+			{Key: "MODIFIED", Value: 8},
+			{Key: "ADDED", Value: 10},
+		}))
+		Expect(len(objects.entries)).To(BeNumerically("<", 10))
+		for _, idx := range []int{2, 4, 6, 8, 10} {
+			objects.remove(fmt.Sprint(idx))
+		}
+		Expect(objects.list()).To(BeEmpty())
+	})
+
+	It("reports errors", func() {
+		wp := GoodPrinter(NewWatchPrinter("{.Name}", ccp)).(*WatchPrinter)
+		PrinterFail(wp, 42)
+		Expect(wp.Fprint(&failingWriter{}, events())).NotTo(Succeed())
+		wp.Redraw = true
+		Expect(wp.Fprint(&failingWriter{}, events())).NotTo(Succeed())
+
+		var out bytes.Buffer
+		ccp.Columns[0].Template = jsonpath.New("zero")
+		Expect(wp.Fprint(&out, events())).NotTo(Succeed())
+		wp.Redraw = false
+		Expect(wp.Fprint(&out, events())).NotTo(Succeed())
+
+		wp.IdentExpr = jsonpath.New("zero")
+		Expect(wp.Fprint(&out, events())).NotTo(Succeed())
+	})
+
+})
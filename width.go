@@ -0,0 +1,261 @@
+// Copyright 2019 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package klo
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// displayWidth returns the number of terminal cells the text s occupies when
+// displayed. ANSI escape sequences are skipped, as they don't take up any
+// space on the display. East Asian wide and fullwidth characters, as well as
+// most emoji, take up two cells, while combining characters take up none.
+func displayWidth(s string) int {
+	width := 0
+	joined := false
+	for len(s) > 0 {
+		if n := escapeLen(s); n > 0 {
+			s = s[n:]
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s)
+		s = s[size:]
+		// A rune following a zero width joiner gets rendered together with
+		// the preceding rune(s) as a single glyph, such as in emoji ZWJ
+		// sequences.
+		if !joined {
+			width += runeWidth(r)
+		}
+		joined = r == zeroWidthJoiner
+	}
+	return width
+}
+
+// truncate returns the text s shortened to at most the specified display
+// width, ending in an ellipsis if s actually needed to be shortened. ANSI
+// escape sequences are kept; if s needed to be shortened and contained any
+// escape sequences, then the shortened text ends in an SGR reset sequence.
+func truncate(s string, width int) string {
+	if displayWidth(s) <= width {
+		return s
+	}
+	if width <= 0 {
+		return ""
+	}
+	// Leave room for the ellipsis at the end.
+	width--
+	var sb strings.Builder
+	w := 0
+	joined := false
+	escaped := false
+	for len(s) > 0 {
+		if n := escapeLen(s); n > 0 {
+			sb.WriteString(s[:n])
+			s = s[n:]
+			escaped = true
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s)
+		rw := runeWidth(r)
+		if joined {
+			rw = 0
+		}
+		if w+rw > width {
+			break
+		}
+		sb.WriteString(s[:size])
+		s = s[size:]
+		w += rw
+		joined = r == zeroWidthJoiner
+	}
+	sb.WriteString(ellipsis)
+	if escaped {
+		sb.WriteString("\x1b[0m")
+	}
+	return sb.String()
+}
+
+const (
+	zeroWidthJoiner = '\u200d'
+	ellipsis        = "…"
+)
+
+// runeWidth returns the number of terminal cells the rune r occupies, based
+// on the Unicode East Asian Width property: either 0 for control and
+// combining characters, 2 for wide and fullwidth characters, or 1 otherwise.
+func runeWidth(r rune) int {
+	switch {
+	case r < 0x20 || (r >= 0x7f && r < 0xa0):
+		return 0
+	case r < 0x300:
+		// Fast path for (Extended) Latin.
+		if r == 0xad {
+			return 0 // soft hyphen
+		}
+		return 1
+	case unicode.In(r, unicode.Mn, unicode.Me, unicode.Cf):
+		return 0
+	case r >= 0x1160 && r <= 0x11ff:
+		return 0 // Hangul medial vowels and final consonants
+	case isWide(r):
+		return 2
+	}
+	return 1
+}
+
+// isWide returns true if the rune r has an East Asian Width property of
+// either Wide (W) or Fullwidth (F).
+func isWide(r rune) bool {
+	idx := sort.Search(len(wideRunes), func(idx int) bool {
+		return wideRunes[idx][1] >= r
+	})
+	return idx < len(wideRunes) && wideRunes[idx][0] <= r
+}
+
+// wideRunes lists the (inclusive) rune ranges with an East Asian Width
+// property of either Wide (W) or Fullwidth (F), ordered by rune. For
+// compactness, some ranges span unassigned code points.
+var wideRunes = [][2]rune{
+	{0x1100, 0x115f}, // Hangul Jamo initial consonants
+	{0x231a, 0x231b},
+	{0x2329, 0x232a},
+	{0x23e9, 0x23ec},
+	{0x23f0, 0x23f0},
+	{0x23f3, 0x23f3},
+	{0x25fd, 0x25fe},
+	{0x2614, 0x2615},
+	{0x2648, 0x2653},
+	{0x267f, 0x267f},
+	{0x2693, 0x2693},
+	{0x26a1, 0x26a1},
+	{0x26aa, 0x26ab},
+	{0x26bd, 0x26be},
+	{0x26c4, 0x26c5},
+	{0x26ce, 0x26ce},
+	{0x26d4, 0x26d4},
+	{0x26ea, 0x26ea},
+	{0x26f2, 0x26f3},
+	{0x26f5, 0x26f5},
+	{0x26fa, 0x26fa},
+	{0x26fd, 0x26fd},
+	{0x2705, 0x2705},
+	{0x270a, 0x270b},
+	{0x2728, 0x2728},
+	{0x274c, 0x274c},
+	{0x274e, 0x274e},
+	{0x2753, 0x2755},
+	{0x2757, 0x2757},
+	{0x2795, 0x2797},
+	{0x27b0, 0x27b0},
+	{0x27bf, 0x27bf},
+	{0x2b1b, 0x2b1c},
+	{0x2b50, 0x2b50},
+	{0x2b55, 0x2b55},
+	{0x2e80, 0x303e}, // CJK radicals, Kangxi radicals, CJK symbols and punctuation
+	{0x3041, 0x33ff}, // Hiragana, Katakana, Bopomofo, ..., CJK compatibility
+	{0x3400, 0x4dbf}, // CJK unified ideographs extension A
+	{0x4e00, 0x9fff}, // CJK unified ideographs
+	{0xa000, 0xa4cf}, // Yi syllables and radicals
+	{0xa960, 0xa97f}, // Hangul Jamo extended-A
+	{0xac00, 0xd7a3}, // Hangul syllables
+	{0xf900, 0xfaff}, // CJK compatibility ideographs
+	{0xfe10, 0xfe19}, // vertical forms
+	{0xfe30, 0xfe6f}, // CJK compatibility forms, small form variants
+	{0xff00, 0xff60}, // fullwidth forms
+	{0xffe0, 0xffe6}, // fullwidth signs
+	{0x16fe0, 0x16fe4},
+	{0x16ff0, 0x16ff1},
+	{0x17000, 0x18cff}, // Tangut, Khitan
+	{0x18d00, 0x18d08},
+	{0x1aff0, 0x1b2ff}, // Kana extended and supplement, Nushu
+	{0x1f004, 0x1f004},
+	{0x1f0cf, 0x1f0cf},
+	{0x1f18e, 0x1f18e},
+	{0x1f191, 0x1f19a},
+	{0x1f200, 0x1f202},
+	{0x1f210, 0x1f23b},
+	{0x1f240, 0x1f248},
+	{0x1f250, 0x1f251},
+	{0x1f260, 0x1f265},
+	{0x1f300, 0x1f320}, // emoji ...
+	{0x1f32d, 0x1f335},
+	{0x1f337, 0x1f37c},
+	{0x1f37e, 0x1f393},
+	{0x1f3a0, 0x1f3ca},
+	{0x1f3cf, 0x1f3d3},
+	{0x1f3e0, 0x1f3f0},
+	{0x1f3f4, 0x1f3f4},
+	{0x1f3f8, 0x1f43e},
+	{0x1f440, 0x1f440},
+	{0x1f442, 0x1f4fc},
+	{0x1f4ff, 0x1f53d},
+	{0x1f54b, 0x1f54e},
+	{0x1f550, 0x1f567},
+	{0x1f57a, 0x1f57a},
+	{0x1f595, 0x1f596},
+	{0x1f5a4, 0x1f5a4},
+	{0x1f5fb, 0x1f64f},
+	{0x1f680, 0x1f6c5},
+	{0x1f6cc, 0x1f6cc},
+	{0x1f6d0, 0x1f6d2},
+	{0x1f6d5, 0x1f6d7},
+	{0x1f6dc, 0x1f6df},
+	{0x1f6eb, 0x1f6ec},
+	{0x1f6f4, 0x1f6fc},
+	{0x1f7e0, 0x1f7eb},
+	{0x1f7f0, 0x1f7f0},
+	{0x1f90c, 0x1f93a},
+	{0x1f93c, 0x1f945},
+	{0x1f947, 0x1f9ff},
+	{0x1fa70, 0x1faff}, // ... emoji
+	{0x20000, 0x2fffd}, // CJK unified ideographs extensions B-F, ...
+	{0x30000, 0x3fffd}, // CJK unified ideographs extensions G-H, ...
+}
+
+// escapeLen returns the length in bytes of the ANSI escape sequence at the
+// beginning of s, or 0 if s doesn't start with an escape sequence. It
+// recognizes CSI ("ESC [") sequences, OSC ("ESC ]") sequences terminated by
+// either BEL or ST ("ESC \"), as well as the other two-byte ESC sequences.
+func escapeLen(s string) int {
+	if len(s) < 2 || s[0] != '\x1b' {
+		return 0
+	}
+	switch s[1] {
+	case '[':
+		// CSI: parameter and intermediate bytes, followed by a single final
+		// byte in the range 0x40-0x7e.
+		for idx := 2; idx < len(s); idx++ {
+			if s[idx] >= 0x40 && s[idx] <= 0x7e {
+				return idx + 1
+			}
+		}
+		return len(s)
+	case ']':
+		// OSC: anything up to either BEL or ST.
+		for idx := 2; idx < len(s); idx++ {
+			if s[idx] == '\a' {
+				return idx + 1
+			}
+			if s[idx] == '\x1b' && idx+1 < len(s) && s[idx+1] == '\\' {
+				return idx + 2
+			}
+		}
+		return len(s)
+	}
+	return 2
+}
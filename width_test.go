@@ -0,0 +1,68 @@
+// Copyright 2019 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package klo
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("display width", func() {
+
+	It("skips ANSI escape sequences", func() {
+		Expect(displayWidth("")).To(Equal(0))
+		Expect(displayWidth("foo")).To(Equal(3))
+		Expect(displayWidth("föö")).To(Equal(3))
+		Expect(displayWidth(Red.Sprint("foo"))).To(Equal(3))
+		Expect(displayWidth("\x1b[1;31")).To(Equal(0))
+		Expect(displayWidth("\x1b]8;;https://example.org\x1b\\link\x1b]8;;\a")).To(Equal(4))
+		Expect(displayWidth("\x1b]0;title")).To(Equal(0))
+		Expect(displayWidth("\x1bcfoo")).To(Equal(3))
+		Expect(displayWidth("\x1b")).To(Equal(0))
+	})
+
+	It("measures East Asian wide characters and emoji", func() {
+		Expect(displayWidth("日本語")).To(Equal(6))
+		Expect(displayWidth("ｆｏｏ")).To(Equal(6))
+		Expect(displayWidth("한국어")).To(Equal(6))
+		Expect(displayWidth("foo🚀")).To(Equal(5))
+		Expect(displayWidth("👩\u200d💻")).To(Equal(2))
+		Expect(displayWidth("\U00020000")).To(Equal(2))
+		Expect(displayWidth("ｶﾀｶﾅ")).To(Equal(4))
+	})
+
+	It("doesn't count combining characters", func() {
+		Expect(displayWidth("e\u0301")).To(Equal(1))
+		Expect(displayWidth("a\u20dd")).To(Equal(1))
+		Expect(displayWidth("foo\u00adbar")).To(Equal(6))
+		Expect(displayWidth("\u1100\u1161\u11a8")).To(Equal(2))
+		Expect(displayWidth("\t\u0085")).To(Equal(0))
+	})
+
+	It("truncates texts to display widths", func() {
+		Expect(truncate("foobar", 10)).To(Equal("foobar"))
+		Expect(truncate("foobar", 6)).To(Equal("foobar"))
+		Expect(truncate("foobar", 4)).To(Equal("foo…"))
+		Expect(truncate("foobar", 1)).To(Equal("…"))
+		Expect(truncate("foobar", 0)).To(Equal(""))
+		Expect(truncate("日本語", 4)).To(Equal("日…"))
+		Expect(truncate("日本語", 5)).To(Equal("日本…"))
+		Expect(truncate("e\u0301e\u0301e\u0301", 2)).To(Equal("e\u0301…"))
+		Expect(truncate("👩\u200d💻👩\u200d💻", 3)).To(Equal("👩\u200d💻…"))
+		Expect(truncate(Red.Sprint("foobar"), 4)).To(Equal("\x1b[31mfoo…\x1b[0m"))
+		Expect(truncate(Red.Sprint("foo"), 4)).To(Equal(Red.Sprint("foo")))
+	})
+
+})
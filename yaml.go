@@ -16,24 +16,101 @@ package klo
 
 import (
 	"io"
+	"iter"
+	"reflect"
+	"strings"
 
 	"sigs.k8s.io/yaml"
 )
 
 // YAMLPrinter prints values in JSON format.
-type YAMLPrinter struct{}
+type YAMLPrinter struct {
+	// Optionally wrap slices, arrays, and streams of items in a
+	// Kubernetes-style List object, except when printing documents.
+	List *ListEnvelope
+	// Print each item of a collection or stream as its own YAML document,
+	// separated by "---" lines, instead of a single YAML sequence. Map
+	// entries get printed as MapEntry objects, while byte slices get
+	// printed as single documents.
+	Documents bool
+}
 
 // NewYAMLPrinter returns a printer for outputting values in YAML format.
 func NewYAMLPrinter() (ValuePrinter, error) {
 	return &YAMLPrinter{}, nil
 }
 
-// Fprint prints a value in YAML format.
+// NewYAMLStreamPrinter returns a printer for outputting values as a stream of
+// YAML documents, with one item per document.
+func NewYAMLStreamPrinter() (ValuePrinter, error) {
+	return &YAMLPrinter{Documents: true}, nil
+}
+
+// Fprint prints a value in YAML format. Streams of items, that is, channels,
+// iter.Seq, and iter.Seq2, get printed item by item as a YAML sequence, or as
+// separate YAML documents when Documents is set.
 func (p *YAMLPrinter) Fprint(w io.Writer, v interface{}) error {
-	txt, err := yaml.Marshal(v)
+	if p.Documents {
+		return p.fprintDocuments(w, v)
+	}
+	if seq, _, ok := stream(v); ok {
+		return p.fprintStream(w, seq)
+	}
+	txt, err := yaml.Marshal(p.List.wrapSlice(v))
 	if err != nil {
 		return err
 	}
 	_, err = w.Write(txt)
 	return err
 }
+
+// fprintDocuments prints each item of a collection or stream as its own YAML
+// document, or otherwise the value v itself as a single document.
+func (p *YAMLPrinter) fprintDocuments(w io.Writer, v interface{}) error {
+	sep := ""
+	return rowObjects(v, func(obj interface{}) error {
+		txt, err := yaml.Marshal(obj)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(w, sep); err != nil {
+			return err
+		}
+		sep = "---\n"
+		_, err = w.Write(txt)
+		return err
+	})
+}
+
+// fprintStream prints the items of a stream as they arrive, producing the
+// same output as if the items were marshalled in a single slice.
+func (p *YAMLPrinter) fprintStream(w io.Writer, seq iter.Seq[reflect.Value]) error {
+	// Without any items, we end up printing just an empty YAML sequence, or
+	// an empty List object; otherwise, we end with the List object's tail,
+	// if any, after the items.
+	head, tail, end := "", "", "[]\n"
+	if p.List != nil {
+		// Marshal the List object with an empty item list, so that the
+		// items then can go in between the List object's head and tail.
+		// Marshalling the List object itself never fails.
+		txt, _ := yaml.Marshal(p.List.wrap([]interface{}{}))
+		end = string(txt)
+		head, tail, _ = strings.Cut(end, "items: []\n")
+		head += "items:\n"
+	}
+	for it := range seq {
+		txt, err := yaml.Marshal(it.Interface())
+		if err != nil {
+			return err
+		}
+		// Turn the item into a sequence item, indenting all but the first
+		// line so they line up with the first line after the "- ".
+		lines := strings.SplitAfter(strings.TrimSuffix(string(txt), "\n"), "\n")
+		if _, err := io.WriteString(w, head+"- "+strings.Join(lines, "  ")+"\n"); err != nil {
+			return err
+		}
+		head, end = "", tail
+	}
+	_, err := io.WriteString(w, end)
+	return err
+}
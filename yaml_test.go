@@ -15,7 +15,13 @@
 package klo
 
 import (
+	"bytes"
+	"slices"
+
+	"sigs.k8s.io/yaml"
+
 	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
 )
 
 var _ = Describe("YAML printer", func() {
@@ -32,9 +38,107 @@ var _ = Describe("YAML printer", func() {
 		// handle it in the printer ... luckily,
 		// https://stackoverflow.com/a/33964549 has the answer as to how make
 		// JSON marshalling fail, which in turn makes the YAML marshaller fail
-		// which we use, kind of a chain reaction...
+		// which we use, kind of a chain reaction... As channels are streams
+		// of items, we use a func instead.
 		p := GoodPrinter(NewYAMLPrinter())
-		PrinterFail(p, make(chan struct{}))
+		PrinterFail(p, func() {})
+		PrinterFail(p, slices.Values([]interface{}{func() {}}))
+		Expect(p.Fprint(&failingWriter{}, slices.Values([]int{42}))).ShouldNot(Succeed())
+		Expect(p.Fprint(&failingWriter{}, slices.Values([]int{}))).ShouldNot(Succeed())
+	})
+
+	It("prints streams of items", func() {
+		type foo struct {
+			Foo string
+			Bar []int
+			Baz map[string]string
+		}
+		items := []interface{}{
+			foo{Foo: "foo", Bar: []int{1, 2}, Baz: map[string]string{"a": "b"}},
+			foo{Foo: "bar"},
+			"foo",
+			[]int{1, 2},
+			map[string]int{},
+		}
+		expected, _ := yaml.Marshal(items)
+		p := GoodPrinter(NewYAMLPrinter())
+		PrinterPass(p, slices.Values(items), string(expected))
+		PrinterPass(p, slices.Values([]foo{}), "[]\n")
+
+		// Block scalars get indented differently, yet still are the same.
+		var out bytes.Buffer
+		Expect(p.Fprint(&out, slices.Values([]string{"multi\nline", "foo"}))).To(Succeed())
+		var actual []string
+		Expect(yaml.Unmarshal(out.Bytes(), &actual)).To(Succeed())
+		Expect(actual).To(Equal([]string{"multi\nline", "foo"}))
+	})
+
+	It("wraps slices in List objects", func() {
+		type foo struct {
+			Foo string
+			Bar []int `json:",omitempty"`
+		}
+		items := []foo{{Foo: "foo", Bar: []int{1, 2}}, {Foo: "bar"}}
+		expected := `apiVersion: v1
+items:
+- Bar:
+  - 1
+  - 2
+  Foo: foo
+- Foo: bar
+kind: List
+metadata: {}
+`
+		p := &YAMLPrinter{List: &ListEnvelope{}}
+		PrinterPass(p, items, expected)
+		PrinterPass(p, [2]foo(items), expected)
+		PrinterPass(p, slices.Values(items), expected)
+		PrinterPass(p, items[1], "Foo: bar\n")
+		PrinterPass(p, []byte("foo"), "Zm9v\n")
+		PrinterPass(p, &tlist{Items: []tlistitem{{A: "x"}}}, "Items:\n- A: x\nKind: \"\"\n")
+
+		expected = "apiVersion: v1\nitems: []\nkind: List\nmetadata: {}\n"
+		PrinterPass(p, []foo(nil), expected)
+		PrinterPass(p, slices.Values([]foo{}), expected)
+
+		p.List = &ListEnvelope{APIVersion: "example.org/v1", Kind: "FooList"}
+		expected = "apiVersion: example.org/v1\nitems:\n- Foo: bar\nkind: FooList\nmetadata: {}\n"
+		PrinterPass(p, items[1:], expected)
+		PrinterPass(p, slices.Values(items[1:]), expected)
+		Expect(p.Fprint(&failingWriter{}, slices.Values(items))).ShouldNot(Succeed())
+	})
+
+	It("prints items as separate documents", func() {
+		type foo struct {
+			Foo string
+			Bar []int `json:",omitempty"`
+		}
+		items := []foo{{Foo: "foo", Bar: []int{1, 2}}, {Foo: "bar"}}
+		expected := `Bar:
+- 1
+- 2
+Foo: foo
+---
+Foo: bar
+`
+		p := GoodPrinter(NewYAMLStreamPrinter())
+		PrinterPass(p, items, expected)
+		PrinterPass(p, [2]foo(items), expected)
+		PrinterPass(p, slices.Values(items), expected)
+		PrinterPass(p, &tlist{Items: []tlistitem{{A: "x"}, {A: "z"}}}, "A: x\n---\nA: z\n")
+		PrinterPass(p, items[1], "Foo: bar\n")
+		PrinterPass(p, map[string]int{"b": 2, "a": 1}, "Key: a\nValue: 1\n---\nKey: b\nValue: 2\n")
+		PrinterPass(p, []foo{}, "")
+		PrinterPass(p, nil, "")
+		PrinterPass(p, []byte("hello"), "aGVsbG8=\n")
+		PrinterPass(p, [][]byte{[]byte("hello"), []byte("world")}, "aGVsbG8=\n---\nd29ybGQ=\n")
+
+		// Documents never get wrapped.
+		PrinterPass(&YAMLPrinter{Documents: true, List: &ListEnvelope{}}, items[1:], "Foo: bar\n")
+
+		PrinterFail(p, []interface{}{func() {}})
+		Expect(p.Fprint(&failingWriter{}, items)).ShouldNot(Succeed())
+		Expect(p.Fprint(&failingWriter{n: 2}, items)).ShouldNot(Succeed())
 	})
 
 })